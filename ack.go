@@ -2,13 +2,36 @@ package gosocketio
 
 import (
 	"errors"
+	"sort"
 	"sync"
 )
 
 var (
-	ErrorWaiterNotFound = errors.New("Waiter not found")
+	ErrorWaiterNotFound  = errors.New("Waiter not found")
+	ErrorAckDisconnected = errors.New("Disconnected before ack response")
 )
 
+/**
+Waits for response to one ack call
+
+packet is kept to resend it after reconnection if resend is set
+*/
+type ackWaiter struct {
+	result chan string
+	err    chan error
+	packet *packet
+	resend bool
+}
+
+func newAckWaiter(p *packet, resend bool) *ackWaiter {
+	return &ackWaiter{
+		result: make(chan string, 1),
+		err:    make(chan error, 1),
+		packet: p,
+		resend: resend,
+	}
+}
+
 /**
 Processes functions that require answers, also known as acknowledge or ack
 */
@@ -16,7 +39,7 @@ type ackProcessor struct {
 	counter     int
 	counterLock sync.Mutex
 
-	resultWaiters     map[int](chan string)
+	resultWaiters     map[int]*ackWaiter
 	resultWaitersLock sync.RWMutex
 }
 
@@ -35,7 +58,7 @@ func (a *ackProcessor) getNextId() int {
 Just before the ack function called, the waiter should be added
 to wait and receive response to ack call
 */
-func (a *ackProcessor) addWaiter(id int, w chan string) {
+func (a *ackProcessor) addWaiter(id int, w *ackWaiter) {
 	a.resultWaitersLock.Lock()
 	a.resultWaiters[id] = w
 	a.resultWaitersLock.Unlock()
@@ -53,7 +76,7 @@ func (a *ackProcessor) removeWaiter(id int) {
 /**
 check if waiter with given ack id is exists, and returns it
 */
-func (a *ackProcessor) getWaiter(id int) (chan string, error) {
+func (a *ackProcessor) getWaiter(id int) (*ackWaiter, error) {
 	a.resultWaitersLock.RLock()
 	defer a.resultWaitersLock.RUnlock()
 
@@ -62,3 +85,44 @@ func (a *ackProcessor) getWaiter(id int) (chan string, error) {
 	}
 	return nil, ErrorWaiterNotFound
 }
+
+/**
+get amount of acks waiting for response
+*/
+func (a *ackProcessor) amount() int {
+	a.resultWaitersLock.RLock()
+	defer a.resultWaitersLock.RUnlock()
+
+	return len(a.resultWaiters)
+}
+
+/**
+Connection is lost, fail acks which packets were sent or dropped with
+outgoing queue, and return packets of those to be resent, ordered by ack id
+Acks still waiting in offline buffer are left untouched
+*/
+func (a *ackProcessor) disconnected(offline *offlineBuffer) []*packet {
+	a.resultWaitersLock.Lock()
+	defer a.resultWaitersLock.Unlock()
+
+	var ids []int
+	for id, w := range a.resultWaiters {
+		if offline != nil && offline.contains(w.packet) {
+			continue
+		}
+		if w.resend && offline != nil && w.packet != nil {
+			ids = append(ids, id)
+			continue
+		}
+
+		delete(a.resultWaiters, id)
+		w.err <- ErrorAckDisconnected
+	}
+
+	sort.Ints(ids)
+	resend := make([]*packet, len(ids))
+	for i, id := range ids {
+		resend[i] = a.resultWaiters[id].packet
+	}
+	return resend
+}
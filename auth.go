@@ -0,0 +1,139 @@
+package gosocketio
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/guhan121/golang-socketio/protocol"
+)
+
+const (
+	//disconnect reason of channel with expired identity
+	ReasonTokenExpired = "token expired"
+)
+
+/**
+Handshake authorization hook of server, called with the request before the
+upgrade. Returned identity is kept by the channel, see Channel.Identity.
+Returned error rejects the request: *HandshakeError is written as its status
+and body, *ConnectError refuses namespace with CONNECT_ERROR packet after the
+upgrade, other errors are written as 401 with error text
+*/
+type AuthorizeFunc func(r *http.Request) (identity interface{}, err error)
+
+/**
+Namespace connect authorization hook of server, called with auth payload of
+client connect packet, empty if there is none. Non nil identity replaces the
+one given by AuthorizeFunc. Returned error refuses namespace with
+CONNECT_ERROR packet and closes connection
+Client must send connect packet, as socket.io v3+ clients and Dial with
+WithAuth do, connection is closed if it does not come in ping timeout
+*/
+type ConnectAuthorizeFunc func(c *Channel, auth json.RawMessage) (identity interface{}, err error)
+
+/**
+Identity which expires, such as JWTClaims, see Server.DisconnectOnExpiry
+*/
+type Expiring interface {
+	//zero time means identity does not expire
+	ExpiresAt() time.Time
+}
+
+/**
+Rejection of handshake with http status and response body
+*/
+type HandshakeError struct {
+	Status int
+	Body   string
+}
+
+func (e *HandshakeError) Error() string {
+	return "Handshake rejected with status " + strconv.Itoa(e.Status) + ": " + e.Body
+}
+
+/**
+Write rejection of handshake request
+*/
+func writeHandshakeError(w http.ResponseWriter, err error) {
+	if he, ok := err.(*HandshakeError); ok {
+		http.Error(w, he.Body, he.Status)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+/**
+Get identity attached to channel by server authorization, nil if none
+*/
+func (c *Channel) Identity() interface{} {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+
+	return c.identity
+}
+
+/**
+Connect packet of client came, authorize it and open channel
+*/
+func (s *Server) connectNamespace(c *Channel, auth string) {
+	if s.AuthorizeConnect == nil || c.State() != StateConnecting {
+		return
+	}
+
+	identity, err := s.AuthorizeConnect(c, json.RawMessage(auth))
+	if err != nil {
+		s.refuseConnect(c, err)
+		return
+	}
+
+	if identity != nil {
+		c.aliveLock.Lock()
+		c.identity = identity
+		c.aliveLock.Unlock()
+	}
+	c.enqueueText(protocol.MustEncode(&protocol.Message{
+		Type:      protocol.MessageTypeEmpty,
+		Namespace: c.namespace,
+	}))
+	s.open(c)
+}
+
+/**
+Send CONNECT_ERROR packet with error, then close connection once it is
+written or ping timeout expires
+*/
+func (s *Server) refuseConnect(c *Channel, err error) {
+	ce, ok := err.(*ConnectError)
+	if !ok {
+		ce = &ConnectError{Message: err.Error()}
+	}
+	data := ce.Data
+	if data == "" {
+		encoded, _ := json.Marshal(map[string]string{"message": ce.Message})
+		data = string(encoded)
+	}
+
+	if conn, _ := c.current(); conn != nil {
+		_, timeout := conn.PingParams()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		c.flushText(ctx, protocol.MustEncode(&protocol.Message{
+			Type:      protocol.MessageTypeConnectError,
+			Namespace: c.namespace,
+			Args:      data,
+		}))
+		cancel()
+	}
+	closeChannel(c, &s.methods, ReasonServerDisconnect)
+}
+
+/**
+Open connecting channel and watch expiry of its identity
+*/
+func (s *Server) open(c *Channel) {
+	if s.transition(c, StateOpen, StateConnecting) {
+		s.watchExpiry(c)
+	}
+}
@@ -1,7 +1,13 @@
 package gosocketio
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
 	"strconv"
+	"sync"
+	"time"
 	"github.com/guhan121/golang-socketio/transport"
 )
 
@@ -9,14 +15,92 @@ const (
 	webSocketProtocol       = "ws://"
 	webSocketSecureProtocol = "wss://"
 	socketioUrl             = "/socket.io/?EIO=3&transport=websocket"
+
+	DefaultHandshakeTimeout = 20 * time.Second
+)
+
+var (
+	ErrorHandshakeTimeout = errors.New("Handshake timeout")
+	ErrorHandshakeClosed  = errors.New("Connection closed during handshake")
 )
 
+/**
+Namespace connect refused by server with CONNECT_ERROR packet
+*/
+type ConnectError struct {
+	//error message sent by server
+	Message string
+	//raw json payload of the packet
+	Data string
+}
+
+func (e *ConnectError) Error() string {
+	return "Connect error: " + e.Message
+}
+
+/**
+Parse CONNECT_ERROR payload, which is a string or an object with message
+*/
+func newConnectError(data string) *ConnectError {
+	e := &ConnectError{Data: data, Message: data}
+
+	var message string
+	var object struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(data), &message) == nil {
+		e.Message = message
+	} else if json.Unmarshal([]byte(data), &object) == nil && object.Message != "" {
+		e.Message = object.Message
+	}
+	return e
+}
+
+/**
+Connecting with one of transports failed, reported by OnTransportError event
+before the next transport is tried
+*/
+type TransportError struct {
+	//engine.io name of failed transport, empty if transport has no name
+	Transport string
+	Err       error
+}
+
+func (e *TransportError) Error() string {
+	return "Transport " + e.Transport + " failed: " + e.Err.Error()
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
 /**
 Socket.io client representation
 */
 type Client struct {
 	methods
 	Channel
+
+	//outgoing queue settings, set before Dial
+	QueueConfig
+	overflooded overfloodRegistry
+
+	//reconnection settings, nil disables reconnection, set before Dial
+	Reconnect *ReconnectConfig
+	//offline buffer settings, nil disables buffering, set before Dial
+	Offline *OfflineConfig
+	//how long connecting waits for engine.io handshake and namespace connect
+	HandshakeTimeout time.Duration
+
+	url           string
+	transports    []transport.Transport
+	transportName string
+
+	closed        bool
+	reconnecting  bool
+	dialing       bool
+	stop          chan struct{}
+	reconnectLock sync.Mutex
 }
 
 /**
@@ -33,38 +117,260 @@ func GetUrl(host string, port int, secure bool) string {
 }
 
 func InitConnect() (*Client) {
-	c := &Client{}
-	c.initChannel()
+	c := &Client{HandshakeTimeout: DefaultHandshakeTimeout}
+	c.QueueBufferSize = queueBufferSize
+	c.stop = make(chan struct{})
+	c.state = StateClosed
 	c.initMethods()
+	c.reconnector = c.reconnectLost
 	return c
 }
 
 /**
-connect to host and initialise socket.io protocol
+connect to host and initialise socket.io protocol using client made by
+InitConnect, see Dial for connecting with options
+Blocks until engine.io handshake and namespace connect are done, returns
+handshake header
 
 The correct ws protocol url example:
 ws://myserver.com/socket.io/?EIO=3&transport=websocket
 
 You can use GetUrlByHost for generating correct url
 */
-func Dial(url string, tr transport.Transport ,c *Client) (error) {
+func DialClient(url string, tr transport.Transport ,c *Client) (Header, error) {
+	c.url = url
+	c.transports = []transport.Transport{tr}
+	if c.Offline != nil && c.offline == nil {
+		c.offline = newOfflineBuffer(*c.Offline)
+	}
+
+	if err := c.connect(context.Background()); err != nil {
+		return Header{}, err
+	}
+	return c.Header, nil
+}
+
+/**
+Open new connection using url and transports given to Dial, start loops and
+wait for handshake and namespace connect
+Transports are tried in order, OnTransportError is fired for every failed one
+and OnTransport with name of the one connected
+Connect error of server and done ctx stop trying, error of the last transport
+is returned
+*/
+func (c *Client) connect(ctx context.Context) error {
+	c.transition(&c.Channel, StateConnecting, StateClosed)
+
+	err := ErrorNoTransports
+	for _, tr := range c.transports {
+		name := transportName(tr)
+		err = c.connectWith(ctx, tr, name)
+		if err == nil {
+			c.callEvent(&c.Channel, OnTransport, name)
+			return nil
+		}
+
+		if _, refused := err.(*ConnectError); refused || ctx.Err() != nil || c.isClosed() {
+			break
+		}
+		c.callEvent(&c.Channel, OnTransportError, &TransportError{name, err})
+	}
 
-	var err error
-	c.conn, err = tr.Connect(url)
+	c.transition(&c.Channel, StateClosed, StateConnecting)
+	return err
+}
+
+/**
+Open connection with one transport, start loops and wait for handshake
+*/
+func (c *Client) connectWith(ctx context.Context, tr transport.Transport,
+	name string) error {
+
+	conn, err := connectTransport(ctx, tr, transportUrl(c.url, name))
 	if err != nil {
 		return err
 	}
-	go procLoop(&c.Channel, &c.methods)
-	go inLoop(&c.Channel, &c.methods)
-	go outLoop(&c.Channel, &c.methods)
-	go pinger(&c.Channel)
 
-	return nil
+	//client closed while dialing, the new connection is not used
+	c.reconnectLock.Lock()
+	if c.closed {
+		c.reconnectLock.Unlock()
+		conn.Close()
+		return ErrorChannelClosed
+	}
+	c.dialing = true
+	defer func() {
+		c.reconnectLock.Lock()
+		c.dialing = false
+		c.reconnectLock.Unlock()
+	}()
+
+	handshake := make(chan error, 1)
+	c.aliveLock.Lock()
+	c.initChannel(&c.methods, c.QueueConfig, &c.overflooded)
+	c.conn = conn
+	c.handshake = handshake
+	c.transportName = name
+	c.aliveLock.Unlock()
+	c.reconnectLock.Unlock()
+
+	ls := c.loops()
+	go procLoop(&c.Channel, &c.methods, ls)
+	go inLoop(&c.Channel, &c.methods, ls)
+	go outLoop(&c.Channel, &c.methods, ls)
+	go pinger(&c.Channel, ls)
+
+	err = waitHandshake(ctx, handshake, c.HandshakeTimeout)
+	if err != nil {
+		closeChannel(&c.Channel, &c.methods, ReasonTransportClose)
+	}
+	return err
+}
+
+/**
+Wait for result of handshake, done ctx or timeout, zero timeout means
+DefaultHandshakeTimeout
+*/
+func waitHandshake(ctx context.Context, handshake chan error, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultHandshakeTimeout
+	}
+
+	select {
+	case err := <-handshake:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return ErrorHandshakeTimeout
+	}
+}
+
+/**
+Engine.io name of transport, empty if transport doesn't tell it
+*/
+func transportName(tr transport.Transport) string {
+	if ntr, ok := tr.(transport.NamedTransport); ok {
+		return ntr.Name()
+	}
+	return ""
+}
+
+/**
+Set transport query param of url to given transport name, transports other
+than websocket get http scheme instead of ws
+Url is kept as is if name is empty
+*/
+func transportUrl(rawurl string, name string) string {
+	u, err := url.Parse(rawurl)
+	if name == "" || err != nil {
+		return rawurl
+	}
+
+	query := u.Query()
+	if query.Get("transport") == name {
+		return rawurl
+	}
+	if name != "websocket" {
+		switch u.Scheme {
+		case "ws":
+			u.Scheme = "http"
+		case "wss":
+			u.Scheme = "https"
+		}
+	}
+	query.Set("transport", name)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+/**
+Connect with context if transport supports it
+*/
+func connectTransport(ctx context.Context, tr transport.Transport,
+	url string) (transport.Connection, error) {
+
+	if ctr, ok := tr.(transport.ContextTransport); ok {
+		return ctr.ConnectContext(ctx, url)
+	}
+	return tr.Connect(url)
+}
+
+/**
+Get engine.io name of transport used by current connection
+*/
+func (c *Client) Transport() string {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+
+	return c.transportName
 }
 
 /**
-Close client connection
+Get amount of client channels with outgoing queue over high watermark
+*/
+func (c *Client) AmountOfOverflooded() int64 {
+	return c.overflooded.amount()
+}
+
+/**
+Get client channels with outgoing queue over high watermark
+*/
+func (c *Client) ListOverflooded() []OverfloodedChannel {
+	return c.overflooded.list()
+}
+
+/**
+Close client connection at once, packets waiting in outgoing queue are lost
 */
 func (c *Client) Close() {
-	closeChannel(&c.Channel, &c.methods)
+	c.stopReconnect()
+	c.transition(&c.Channel, StateClosing, StateOpen)
+	closeChannel(&c.Channel, &c.methods, ReasonClientDisconnect)
+	c.transition(&c.Channel, StateClosed)
+}
+
+/**
+Close client gracefully: leave namespace, close engine.io session and write
+everything queued before, then close connection
+Connection is closed anyway when ctx is done, its error is returned then
+*/
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.shutdown(ctx, c.namespace)
+}
+
+/**
+Leave given namespaces and close connection gracefully
+*/
+func (c *Client) shutdown(ctx context.Context, namespaces ...string) error {
+	c.stopReconnect()
+	c.closing(ReasonClientDisconnect)
+	c.transition(&c.Channel, StateClosing, StateOpen)
+	err := c.flushClose(ctx, true, namespaces...)
+	closeChannel(&c.Channel, &c.methods, ReasonClientDisconnect)
+	c.transition(&c.Channel, StateClosed)
+	return err
+}
+
+/**
+Check if client was closed by Close or Shutdown
+*/
+func (c *Client) isClosed() bool {
+	c.reconnectLock.Lock()
+	defer c.reconnectLock.Unlock()
+
+	return c.closed
+}
+
+/**
+Mark client closed, so it is not reconnected anymore
+*/
+func (c *Client) stopReconnect() {
+	c.reconnectLock.Lock()
+	defer c.reconnectLock.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.stop)
+	}
 }
@@ -0,0 +1,260 @@
+package gosocketio
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialWaitsForHandshake(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	c, err := Dial(context.Background(), ts.URL, WithHandshakeTimeout(5*time.Second))
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.NotEmpty(t, c.Id())
+	assert.True(t, c.IsAlive())
+}
+
+
+/**
+Test server writing given packets to every websocket connection
+*/
+func packetServer(t *testing.T, packets ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		socket, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer socket.Close()
+
+		for _, p := range packets {
+			socket.WriteMessage(websocket.TextMessage, []byte(p))
+		}
+		socket.ReadMessage()
+	}))
+}
+
+func TestDialConnectError(t *testing.T) {
+	ts := packetServer(t, `0{"sid":"abc","pingInterval":25000,"pingTimeout":60000}`,
+		`44{"message":"Not authorized"}`)
+	defer ts.Close()
+
+	_, err := Dial(context.Background(), ts.URL)
+	require.IsType(t, &ConnectError{}, err)
+	assert.Equal(t, "Not authorized", err.(*ConnectError).Message)
+}
+
+func TestDialHandshakeTimeout(t *testing.T) {
+	ts := packetServer(t, `0{"sid":"abc","pingInterval":25000,"pingTimeout":60000}`)
+	defer ts.Close()
+
+	_, err := Dial(context.Background(), ts.URL, WithHandshakeTimeout(100*time.Millisecond))
+	assert.Equal(t, ErrorHandshakeTimeout, err)
+}
+
+/**
+Local http CONNECT proxy counting tunnels it made
+*/
+func connectProxy(t *testing.T, tunnels *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "connect only", http.StatusMethodNotAllowed)
+			return
+		}
+		dst, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer dst.Close()
+
+		src, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer src.Close()
+		atomic.AddInt32(tunnels, 1)
+
+		src.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(dst, src)
+		io.Copy(src, dst)
+	}))
+}
+
+/**
+Self-signed certificate usable as client certificate and its own CA
+*/
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestDefaultTransportNoProxy(t *testing.T) {
+	assert.Nil(t, transport.GetDefaultWebsocketTransport().Proxy)
+}
+
+func TestDialThroughProxyWithMutualTLS(t *testing.T) {
+	clientCert, clientCAs := selfSignedCert(t)
+
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	ts := httptest.NewUnstartedServer(server)
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	var tunnels int32
+	proxy := connectProxy(t, &tunnels)
+	defer proxy.Close()
+	proxyUrl, _ := url.Parse(proxy.URL)
+
+	serverCAs := x509.NewCertPool()
+	serverCAs.AddCert(ts.Certificate())
+
+	tr := transport.GetDefaultWebsocketTransport()
+	tr.Proxy = http.ProxyURL(proxyUrl)
+	tr.TLSClientConfig = &tls.Config{RootCAs: serverCAs, Certificates: []tls.Certificate{clientCert}}
+
+	c, err := Dial(context.Background(), ts.URL, WithTransports(tr))
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tunnels))
+
+	tr.TLSClientConfig = &tls.Config{RootCAs: serverCAs}
+	_, err = Dial(context.Background(), ts.URL, WithTransports(tr))
+	assert.Error(t, err)
+}
+
+/**
+Transport which always fails, remembers url it was asked to connect
+*/
+type failingTransport struct {
+	transport.WebsocketTransport
+	url string
+}
+
+func (ft *failingTransport) Name() string {
+	return "polling"
+}
+
+func (ft *failingTransport) ConnectContext(ctx context.Context,
+	url string) (transport.Connection, error) {
+
+	ft.url = url
+	return nil, errors.New("polling refused")
+}
+
+func TestDialTransportFallback(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	failing := &failingTransport{}
+	var failed *TransportError
+	var used string
+	c, err := Dial(context.Background(), ts.URL,
+		WithTransports(failing, transport.GetDefaultWebsocketTransport()),
+		WithHandler(OnTransportError, func(h *Channel, err *TransportError) {
+			failed = err
+		}),
+		WithHandler(OnTransport, func(h *Channel, name string) {
+			used = name
+		}),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.Equal(t, "websocket", c.Transport())
+	assert.Equal(t, "websocket", used)
+	require.NotNil(t, failed)
+	assert.Equal(t, "polling", failed.Transport)
+	assert.Contains(t, failing.url, "http://")
+	assert.Contains(t, failing.url, "transport=polling")
+}
+
+func TestClientShutdownFlushes(t *testing.T) {
+	received := make(chan int, 100)
+	disconnected := make(chan struct{})
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.On("count", func(h *Channel, data []byte) {
+		received <- len(data)
+	})
+	server.On(OnDisconnection, func(h *Channel) {
+		close(disconnected)
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	reconnects := int32(0)
+	c, err := Dial(context.Background(), ts.URL,
+		WithReconnect(&ReconnectConfig{InitialDelay: time.Millisecond}),
+		WithHandler(OnReconnectAttempt, func(h *Channel) {
+			atomic.AddInt32(&reconnects, 1)
+		}),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, c.Emit("count", i))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, c.Shutdown(ctx))
+
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not see disconnect")
+	}
+	assert.Len(t, received, 20)
+	assert.False(t, c.IsAlive())
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&reconnects))
+}
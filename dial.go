@@ -0,0 +1,330 @@
+package gosocketio
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+)
+
+const (
+	DefaultPath = "/socket.io/"
+)
+
+var (
+	ErrorNoTransports = errors.New("No transports to connect with")
+	ErrorWrongScheme  = errors.New("Url scheme should be http, https, ws or wss")
+)
+
+/**
+Option of Dial
+*/
+type DialOption func(*dialConfig)
+
+type dialHandler struct {
+	method string
+	f      interface{}
+}
+
+type dialConfig struct {
+	header           http.Header
+	query            url.Values
+	auth             interface{}
+	tokenRefresh     func() (interface{}, error)
+	path             string
+	namespace        string
+	transports       []transport.Transport
+	handshakeTimeout time.Duration
+	tlsConfig        *tls.Config
+
+	queue     *QueueConfig
+	reconnect *ReconnectConfig
+	offline   *OfflineConfig
+	handlers  []dialHandler
+}
+
+/**
+Extra http headers of the handshake request
+*/
+func WithHeader(header http.Header) DialOption {
+	return func(dc *dialConfig) {
+		for key, values := range header {
+			for _, value := range values {
+				dc.header.Add(key, value)
+			}
+		}
+	}
+}
+
+/**
+Extra query params of the handshake request
+*/
+func WithQuery(query url.Values) DialOption {
+	return func(dc *dialConfig) {
+		for key, values := range query {
+			for _, value := range values {
+				dc.query.Add(key, value)
+			}
+		}
+	}
+}
+
+/**
+Auth payload sent as json with namespace connect packet
+*/
+func WithAuth(auth interface{}) DialOption {
+	return func(dc *dialConfig) {
+		dc.auth = auth
+	}
+}
+
+/**
+Answer OnTokenRequest of server with fresh auth payload given by f, see
+Channel.RefreshToken. Request is left unanswered if f returns error
+*/
+func WithTokenRefresh(f func() (auth interface{}, err error)) DialOption {
+	return func(dc *dialConfig) {
+		dc.tokenRefresh = f
+	}
+}
+
+/**
+Path of socket.io endpoint, DefaultPath if url has no path
+*/
+func WithPath(path string) DialOption {
+	return func(dc *dialConfig) {
+		dc.path = path
+	}
+}
+
+/**
+Connect to given namespace instead of default "/"
+*/
+func WithNamespace(namespace string) DialOption {
+	return func(dc *dialConfig) {
+		dc.namespace = namespace
+	}
+}
+
+/**
+Transports to connect with, tried in given order, next one is used if
+connecting or handshake with previous one fails
+Default is websocket transport with default params
+*/
+func WithTransports(transports ...transport.Transport) DialOption {
+	return func(dc *dialConfig) {
+		dc.transports = transports
+	}
+}
+
+/**
+Timeout of websocket handshake, also used as timeout of waiting for
+engine.io handshake and namespace connect
+*/
+func WithHandshakeTimeout(timeout time.Duration) DialOption {
+	return func(dc *dialConfig) {
+		dc.handshakeTimeout = timeout
+	}
+}
+
+/**
+TLS settings for wss and https urls
+*/
+func WithTLSConfig(config *tls.Config) DialOption {
+	return func(dc *dialConfig) {
+		dc.tlsConfig = config
+	}
+}
+
+/**
+Outgoing queue settings
+*/
+func WithQueueConfig(config QueueConfig) DialOption {
+	return func(dc *dialConfig) {
+		dc.queue = &config
+	}
+}
+
+/**
+Enable reconnection with given settings
+*/
+func WithReconnect(config *ReconnectConfig) DialOption {
+	return func(dc *dialConfig) {
+		dc.reconnect = config
+	}
+}
+
+/**
+Enable offline buffer with given settings
+*/
+func WithOfflineBuffer(config *OfflineConfig) DialOption {
+	return func(dc *dialConfig) {
+		dc.offline = config
+	}
+}
+
+/**
+Register message or event handler before connecting, so no event is missed
+*/
+func WithHandler(method string, f interface{}) DialOption {
+	return func(dc *dialConfig) {
+		dc.handlers = append(dc.handlers, dialHandler{method, f})
+	}
+}
+
+/**
+Build engine.io websocket url from server url and options
+*/
+func (dc *dialConfig) buildUrl(rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+
+	switch u.Scheme {
+	case "http", "ws":
+		u.Scheme = "ws"
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		return "", ErrorWrongScheme
+	}
+
+	if dc.path != "" {
+		u.Path = dc.path
+	} else if u.Path == "" || u.Path == "/" {
+		u.Path = DefaultPath
+	}
+
+	query := u.Query()
+	for key, values := range dc.query {
+		query[key] = append(query[key], values...)
+	}
+	query.Set("EIO", "3")
+	query.Set("transport", "websocket")
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+/**
+Apply headers, tls and handshake timeout to copies of websocket transports
+*/
+func (dc *dialConfig) buildTransports() []transport.Transport {
+	transports := dc.transports
+	if len(transports) == 0 {
+		transports = []transport.Transport{transport.GetDefaultWebsocketTransport()}
+	}
+
+	result := make([]transport.Transport, len(transports))
+	for i, tr := range transports {
+		wst, ok := tr.(*transport.WebsocketTransport)
+		if !ok {
+			result[i] = tr
+			continue
+		}
+
+		cp := *wst
+		cp.RequestHeader = http.Header{}
+		for key, values := range wst.RequestHeader {
+			cp.RequestHeader[key] = append([]string{}, values...)
+		}
+		for key, values := range dc.header {
+			cp.RequestHeader[key] = append(cp.RequestHeader[key], values...)
+		}
+		if dc.tlsConfig != nil {
+			cp.TLSClientConfig = dc.tlsConfig
+		}
+		if dc.handshakeTimeout > 0 {
+			cp.HandshakeTimeout = dc.handshakeTimeout
+		}
+		result[i] = &cp
+	}
+	return result
+}
+
+/**
+Connect to socket.io server with given options and return connected client
+Blocks until engine.io handshake and namespace connect are done, ctx is done,
+or handshake timeout expires, CONNECT_ERROR of server is returned as
+*ConnectError
+
+Server url may be http(s) or ws(s) with or without path, for example
+http://myserver.com:3811, engine.io path and query are added to it
+*/
+func Dial(ctx context.Context, rawurl string, opts ...DialOption) (*Client, error) {
+	c, err := newDialClient(rawurl, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+/**
+Make client configured by dial options, not connected yet
+*/
+func newDialClient(rawurl string, opts []DialOption) (*Client, error) {
+	dc := &dialConfig{
+		header: http.Header{},
+		query:  url.Values{},
+	}
+	for _, opt := range opts {
+		opt(dc)
+	}
+
+	engineUrl, err := dc.buildUrl(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	c := InitConnect()
+	for _, h := range dc.handlers {
+		if err := c.On(h.method, h.f); err != nil {
+			return nil, err
+		}
+	}
+
+	if refresh := dc.tokenRefresh; refresh != nil {
+		c.On(OnTokenRequest, func(h *Channel) {
+			go func() {
+				if auth, err := refresh(); err == nil {
+					h.RefreshToken(auth)
+				}
+			}()
+		})
+	}
+	if dc.auth != nil {
+		auth, err := json.Marshal(dc.auth)
+		if err != nil {
+			return nil, err
+		}
+		c.auth = string(auth)
+	}
+	if dc.namespace != "/" {
+		c.namespace = dc.namespace
+	}
+	if dc.queue != nil {
+		c.QueueConfig = *dc.queue
+	}
+	if dc.handshakeTimeout > 0 {
+		c.HandshakeTimeout = dc.handshakeTimeout
+	}
+	c.Reconnect = dc.reconnect
+	c.Offline = dc.offline
+	if c.Offline != nil {
+		c.offline = newOfflineBuffer(*c.Offline)
+	}
+
+	c.url = engineUrl
+	c.transports = dc.buildTransports()
+	return c, nil
+}
@@ -0,0 +1,41 @@
+package gosocketio
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialBuildUrl(t *testing.T) {
+	dc := &dialConfig{header: http.Header{}, query: url.Values{}}
+	WithQuery(url.Values{"token": {"abc"}})(dc)
+
+	u, err := dc.buildUrl("http://localhost:3811")
+	assert.NoError(t, err)
+	assert.Equal(t, "ws://localhost:3811/socket.io/?EIO=3&token=abc&transport=websocket", u)
+
+	WithPath("/custom/")(dc)
+	u, err = dc.buildUrl("wss://example.com/?room=1")
+	assert.NoError(t, err)
+	assert.Equal(t, "wss://example.com/custom/?EIO=3&room=1&token=abc&transport=websocket", u)
+
+	_, err = dc.buildUrl("ftp://example.com")
+	assert.Equal(t, ErrorWrongScheme, err)
+}
+
+func TestDialBuildTransports(t *testing.T) {
+	tr := transport.GetDefaultWebsocketTransport()
+	tr.RequestHeader = http.Header{"Cookie": {"a=b"}}
+
+	dc := &dialConfig{header: http.Header{}, query: url.Values{}}
+	WithTransports(tr)(dc)
+	WithHeader(http.Header{"Authorization": {"Bearer x"}})(dc)
+
+	built := dc.buildTransports()[0].(*transport.WebsocketTransport)
+	assert.Equal(t, "a=b", built.RequestHeader.Get("Cookie"))
+	assert.Equal(t, "Bearer x", built.RequestHeader.Get("Authorization"))
+	assert.Empty(t, tr.RequestHeader.Get("Authorization"))
+}
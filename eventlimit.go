@@ -0,0 +1,121 @@
+package gosocketio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/guhan121/golang-socketio/protocol"
+)
+
+const (
+	//event emitted to client with name of event over limit, RateLimitError
+	OnRateLimit = "rate_limit"
+
+	//disconnect reason of RateLimitDisconnect
+	ReasonRateLimit = "rate limit exceeded"
+)
+
+/**
+What server does with incoming event over rate limit
+*/
+type RateLimitAction int
+
+const (
+	//event is dropped silently, ack is not sent
+	RateLimitDrop RateLimitAction = iota
+	//event is dropped and OnRateLimit is emitted to client
+	RateLimitError
+	//event is dropped and client is disconnected
+	RateLimitDisconnect
+)
+
+/**
+Token bucket limit, Rate events per second, up to Burst at once, burst
+defaults to rate. Zero Rate means no limit
+*/
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+/**
+Limits of incoming events of every channel of server
+Event must fit both limit of the channel and limit of its name
+*/
+type EventLimits struct {
+	//limit of all events of channel
+	RateLimit
+	//limits of events by name
+	Events map[string]RateLimit
+
+	Action RateLimitAction
+	//called with every event over limit, before action is taken
+	OnViolation func(c *Channel, event string)
+}
+
+/**
+Buckets of incoming events of one channel
+*/
+type eventLimiter struct {
+	all    tokenBucket
+	events map[string]*tokenBucket
+	lock   sync.Mutex
+}
+
+/**
+Take token of event from channel and event buckets, returns false if
+event is over limit
+*/
+func (l *eventLimiter) allow(limits *EventLimits, event string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	limit, limited := limits.Events[event]
+	limited = limited && limit.Rate > 0
+
+	//check event bucket first, so token of channel is not spent for nothing
+	var bucket *tokenBucket
+	if limited {
+		if bucket = l.events[event]; bucket == nil {
+			if l.events == nil {
+				l.events = make(map[string]*tokenBucket)
+			}
+			bucket = &tokenBucket{}
+			l.events[event] = bucket
+		}
+		if !bucket.take(limit.Rate, limit.Burst, now) {
+			return false
+		}
+	}
+
+	if limits.Rate > 0 && !l.all.take(limits.Rate, limits.Burst, now) {
+		if limited {
+			//give back token of event
+			bucket.tokens++
+		}
+		return false
+	}
+	return true
+}
+
+/**
+Check incoming event against limits of server and take action if it is over
+*/
+func (s *Server) limitEvent(c *Channel, msg *protocol.Message) bool {
+	limits := s.EventLimits
+	if limits == nil || c.events.allow(limits, msg.Method) {
+		return true
+	}
+
+	if limits.OnViolation != nil {
+		limits.OnViolation(c, msg.Method)
+	}
+	switch limits.Action {
+	case RateLimitError:
+		c.Emit(OnRateLimit, msg.Method)
+	case RateLimitDisconnect:
+		c.Disconnect(ReasonRateLimit, true)
+	}
+	return false
+}
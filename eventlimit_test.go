@@ -0,0 +1,67 @@
+package gosocketio
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLimiter(t *testing.T) {
+	limits := &EventLimits{
+		RateLimit: RateLimit{Rate: 0.001, Burst: 3},
+		Events:    map[string]RateLimit{"spam": {Rate: 0.001, Burst: 1}},
+	}
+
+	l := eventLimiter{}
+	assert.True(t, l.allow(limits, "spam"))
+	assert.False(t, l.allow(limits, "spam"))
+	assert.True(t, l.allow(limits, "chat"))
+	assert.True(t, l.allow(limits, "chat"))
+	assert.False(t, l.allow(limits, "chat"))
+}
+
+func TestServerEventLimits(t *testing.T) {
+	handled := int32(0)
+	violations := int32(0)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.EventLimits = &EventLimits{
+		Events: map[string]RateLimit{"spam": {Rate: 0.001, Burst: 2}},
+		Action: RateLimitError,
+		OnViolation: func(c *Channel, event string) {
+			atomic.AddInt32(&violations, 1)
+		},
+	}
+	server.On("spam", func(h *Channel) {
+		atomic.AddInt32(&handled, 1)
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	limited := make(chan struct{}, 5)
+	c, err := Dial(context.Background(), ts.URL,
+		WithHandler(OnRateLimit, func(h *Channel) {
+			limited <- struct{}{}
+		}),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, c.Emit("spam", nil))
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-limited:
+		case <-time.After(5 * time.Second):
+			t.Fatal("rate limit was not reported")
+		}
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&handled))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&violations))
+}
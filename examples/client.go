@@ -1,8 +1,8 @@
 package main
 
 import (
-	"github.com/graarh/golang-socketio"
-	"github.com/graarh/golang-socketio/transport"
+	"context"
+	"github.com/guhan121/golang-socketio"
 	"log"
 	"runtime"
 	"time"
@@ -31,35 +31,29 @@ func sendJoin(c *gosocketio.Client) {
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
-	c, err := gosocketio.Dial(
-		gosocketio.GetUrl("localhost", 3811, false),
-		transport.GetDefaultWebsocketTransport())
+	c, err := gosocketio.Dial(context.Background(), "http://localhost:3811",
+		gosocketio.WithHandshakeTimeout(10*time.Second),
+		gosocketio.WithReconnect(gosocketio.GetDefaultReconnectConfig()),
+		gosocketio.WithHandler("/message", func(h *gosocketio.Channel, args Message) {
+			log.Println("--- Got chat message: ", args)
+		}),
+		gosocketio.WithHandler(gosocketio.OnConnection, func(h *gosocketio.Channel) {
+			log.Println("Connected")
+		}),
+		gosocketio.WithHandler(gosocketio.OnDisconnection, func(h *gosocketio.Channel) {
+			log.Println("Disconnected")
+		}),
+		gosocketio.WithHandler(gosocketio.OnReconnectAttempt, func(h *gosocketio.Channel, attempt int) {
+			log.Println("Reconnecting, attempt", attempt)
+		}),
+		gosocketio.WithHandler(gosocketio.OnReconnectFailed, func(h *gosocketio.Channel) {
+			log.Fatal("Reconnection failed")
+		}),
+	)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	err = c.On("/message", func(h *gosocketio.Channel, args Message) {
-		log.Println("--- Got chat message: ", args)
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	err = c.On(gosocketio.OnDisconnection, func(h *gosocketio.Channel) {
-		log.Fatal("Disconnected")
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	err = c.On(gosocketio.OnConnection, func(h *gosocketio.Channel) {
-		log.Println("Connected")
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	time.Sleep(1 * time.Second)
+	log.Println("Connected, sid", c.Id())
 
 	go sendJoin(c)
 	go sendJoin(c)
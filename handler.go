@@ -1,6 +1,7 @@
 package gosocketio
 
 import (
+	"reflect"
 	"sync"
 	"github.com/guhan121/golang-socketio/protocol"
 
@@ -11,6 +12,16 @@ const (
 	OnConnection    = "connection"
 	OnDisconnection = "disconnection"
 	OnError         = "error"
+
+	OnReconnectAttempt = "reconnect_attempt"
+	OnReconnect        = "reconnect"
+	OnReconnectError   = "reconnect_error"
+	OnReconnectFailed  = "reconnect_failed"
+
+	OnTransport      = "transport"
+	OnTransportError = "transport_error"
+
+	OnStateChange = "state_change"
 )
 
 /**
@@ -27,6 +38,16 @@ type methods struct {
 
 	onConnection    systemHandler
 	onDisconnection systemHandler
+	//server only, channel closed before getting open
+	onAbort systemHandler
+
+	//client manager only, gets packets of every namespace instead of handlers
+	onPacket func(c *Channel, msg *protocol.Message)
+	//client only, returns function reconnecting lost connection, nil if it
+	//should not be reconnected
+	reconnector func(c *Channel) func()
+	//server only, returns false if incoming event should be dropped
+	allowEvent func(c *Channel, msg *protocol.Message) bool
 }
 
 /**
@@ -78,7 +99,44 @@ func (m *methods) callLoopEvent(c *Channel, event string) {
 	f.callFunc(c, &struct{}{})
 }
 
+/**
+Call event handler, passing arg if handler accepts value of its type,
+handler with other argument type gets its zero value
+*/
+func (m *methods) callEvent(c *Channel, event string, arg interface{}) {
+	f, ok := m.findMethod(event)
+	if !ok {
+		return
+	}
+
+	if !f.ArgsPresent {
+		f.callFunc(c, &struct{}{})
+		return
+	}
+	if arg == nil || !reflect.TypeOf(arg).AssignableTo(f.Args) {
+		f.callFunc(c, nil)
+		return
+	}
+
+	value := reflect.New(reflect.TypeOf(arg))
+	value.Elem().Set(reflect.ValueOf(arg))
+	f.callFunc(c, value.Interface())
+}
+
 func (m *methods) processIncomingMessage(c *Channel, msg *protocol.Message) {
+	if m.onPacket != nil {
+		m.onPacket(c, msg)
+		return
+	}
+	if msg.Namespace != c.namespace {
+		//packet of other namespace sharing the connection
+		return
+	}
+	if m.allowEvent != nil && (msg.Type == protocol.MessageTypeEmit ||
+		msg.Type == protocol.MessageTypeAckRequest) && !m.allowEvent(c, msg) {
+		return
+	}
+
 	switch msg.Type {
 	case protocol.MessageTypeEmit:
 		f, ok := m.findMethod(msg.Method)
@@ -110,7 +168,10 @@ func (m *methods) processIncomingMessage(c *Channel, msg *protocol.Message) {
 	case protocol.MessageTypeAckResponse:
 		waiter, err := c.ack.getWaiter(msg.AckId)
 		if err == nil {
-			waiter <- msg.Args
+			select {
+			case waiter.result <- msg.Args:
+			default:
+			}
 		}
 	default:
 
@@ -0,0 +1,146 @@
+package gosocketio
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const (
+	HeaderForwarded = "Forwarded"
+)
+
+/**
+Get address of request peer, RemoteAddr with or without port
+*/
+func parseRemoteAddr(remoteAddr string) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(remoteAddr); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	if addr, err := netip.ParseAddr(remoteAddr); err == nil {
+		return addr.Unmap()
+	}
+	return netip.Addr{}
+}
+
+/**
+Parse node of X-Forwarded-For or for parameter of Forwarded header, which
+may be quoted and may have port, ipv6 in brackets
+*/
+func parseForwardedNode(node string) (netip.Addr, bool) {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.IndexByte(node, ']'); end != -1 {
+			node = node[1:end]
+		}
+	} else if strings.Count(node, ":") == 1 {
+		node = node[:strings.IndexByte(node, ':')]
+	}
+
+	addr, err := netip.ParseAddr(node)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+/**
+Split header values by commas out of quoted strings
+*/
+func splitHeaderList(values []string) []string {
+	var result []string
+	for _, value := range values {
+		start, quoted := 0, false
+		for i := 0; i < len(value); i++ {
+			switch value[i] {
+			case '"':
+				quoted = !quoted
+			case ',':
+				if !quoted {
+					result = append(result, value[start:i])
+					start = i + 1
+				}
+			}
+		}
+		result = append(result, value[start:])
+	}
+	return result
+}
+
+/**
+Get chain of forwarded addresses from Forwarded header if name is
+HeaderForwarded, or from X-Forwarded-For otherwise, leftmost is the original
+client. The other header is ignored, it may be set by client
+Nodes which are not ip addresses, like "unknown", are kept as invalid ones
+*/
+func forwardedChain(header http.Header, name string) []netip.Addr {
+	var chain []netip.Addr
+
+	if http.CanonicalHeaderKey(name) == HeaderForwarded {
+		for _, element := range splitHeaderList(header.Values(HeaderForwarded)) {
+			addr := netip.Addr{}
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					addr, _ = parseForwardedNode(value)
+				}
+			}
+			chain = append(chain, addr)
+		}
+		return chain
+	}
+
+	for _, node := range splitHeaderList(header.Values(HeaderForward)) {
+		addr, _ := parseForwardedNode(node)
+		chain = append(chain, addr)
+	}
+	return chain
+}
+
+/**
+Check if address belongs to one of networks
+*/
+func inNetworks(addr netip.Addr, networks []netip.Prefix) bool {
+	for _, prefix := range networks {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+Resolve address of client behind trusted proxies
+
+Forwarded header of given name is used only if peer is a trusted proxy.
+Chain is walked from right to left and the first address which is not
+a trusted proxy is the client. Walking stops at invalid node, giving the last
+valid one
+*/
+func resolveClientIp(remoteAddr string, header http.Header, name string,
+	trusted []netip.Prefix) netip.Addr {
+
+	addr := parseRemoteAddr(remoteAddr)
+	if !addr.IsValid() || !inNetworks(addr, trusted) {
+		return addr
+	}
+
+	chain := forwardedChain(header, name)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if !chain[i].IsValid() {
+			return addr
+		}
+		addr = chain[i]
+		if !inNetworks(addr, trusted) {
+			return addr
+		}
+	}
+	return addr
+}
+
+/**
+Get client ip of request, see resolveClientIp
+*/
+func (s *Server) clientIp(r *http.Request) netip.Addr {
+	return resolveClientIp(r.RemoteAddr, r.Header, s.ProxyHeader, s.TrustedProxies)
+}
@@ -0,0 +1,126 @@
+package gosocketio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveClientIp(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+
+	cases := []struct {
+		remote string
+		header http.Header
+		ip     string
+	}{
+		{"203.0.113.1:4000", nil, "203.0.113.1"},
+		//untrusted peer can not spoof
+		{"203.0.113.1:4000", http.Header{"X-Forwarded-For": {"1.2.3.4"}}, "203.0.113.1"},
+		{"10.0.0.1:4000", http.Header{"X-Forwarded-For": {"1.2.3.4, 10.0.0.2"}}, "1.2.3.4"},
+		//spoofed leftmost entry is skipped
+		{"10.0.0.1:4000", http.Header{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4"}}, "1.2.3.4"},
+		{"10.0.0.1:4000", http.Header{"X-Forwarded-For": {"6.6.6.6", "1.2.3.4, 10.0.0.3"}}, "1.2.3.4"},
+		{"10.0.0.1:4000", http.Header{"X-Forwarded-For": {"10.0.0.5"}}, "10.0.0.5"},
+		{"10.0.0.1:4000", http.Header{"X-Forwarded-For": {"garbage, 10.0.0.5"}}, "10.0.0.5"},
+		//Forwarded set by client behind X-Forwarded-For proxy is not trusted
+		{"10.0.0.1:4000", http.Header{"Forwarded": {`For="198.51.100.7:80"`}, "X-Forwarded-For": {"1.2.3.4"}}, "1.2.3.4"},
+		{"10.0.0.1:4000", http.Header{"Forwarded": {"for=198.51.100.7"}}, "10.0.0.1"},
+		{"[::ffff:10.0.0.1]:4000", http.Header{"X-Forwarded-For": {"1.2.3.4"}}, "1.2.3.4"},
+		{"bad", nil, "invalid IP"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.ip, resolveClientIp(tc.remote, tc.header, "", trusted).String(), tc.remote, tc.header)
+	}
+
+	forwarded := []struct {
+		remote string
+		header http.Header
+		ip     string
+	}{
+		{"10.0.0.1:4000", http.Header{"Forwarded": {`for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"`}}, "192.0.2.60"},
+		{"10.0.0.1:4000", http.Header{"Forwarded": {`For="198.51.100.7:80"`}, "X-Forwarded-For": {"1.2.3.4"}}, "198.51.100.7"},
+		//X-Forwarded-For set by client behind Forwarded proxy is not trusted
+		{"10.0.0.1:4000", http.Header{"X-Forwarded-For": {"1.2.3.4"}}, "10.0.0.1"},
+		{"10.0.0.1:4000", http.Header{"Forwarded": {"for=unknown"}}, "10.0.0.1"},
+	}
+	for _, tc := range forwarded {
+		assert.Equal(t, tc.ip, resolveClientIp(tc.remote, tc.header, HeaderForwarded, trusted).String(), tc.remote, tc.header)
+	}
+}
+
+func TestServerProxyHeader(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+	ips := make(chan netip.Addr, 1)
+	server.On(OnConnection, func(h *Channel) {
+		ips <- h.Ip()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	//proxy sets X-Forwarded-For, client spoofs Forwarded
+	c, err := Dial(context.Background(), ts.URL, WithHeader(http.Header{
+		"Forwarded":       {"for=198.51.100.7"},
+		"X-Forwarded-For": {"203.0.113.9"},
+	}))
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, "203.0.113.9", (<-ips).String())
+}
+
+func TestIpFilter(t *testing.T) {
+	allow, err := ParsePrefixes([]string{"10.0.0.0/8", "192.168.1.7"})
+	require.NoError(t, err)
+	deny, err := ParsePrefixes([]string{"10.1.0.0/16"})
+	require.NoError(t, err)
+	_, err = ParsePrefixes([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	f := &IpFilter{}
+	assert.True(t, f.Allowed(netip.MustParseAddr("8.8.8.8")))
+	assert.True(t, f.Allowed(netip.Addr{}))
+
+	f.Set(allow, deny)
+	for ip, allowed := range map[string]bool{
+		"10.2.3.4":        true,
+		"::ffff:10.2.3.4": true,
+		"10.1.2.3":        false,
+		"192.168.1.7":     true,
+		"192.168.1.8":     false,
+		"8.8.8.8":         false,
+		"2001:db8::1":     false,
+	} {
+		assert.Equal(t, allowed, f.Allowed(netip.MustParseAddr(ip)), ip)
+	}
+	assert.False(t, f.Allowed(netip.Addr{}))
+}
+
+func TestServerIpFilter(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.IpFilter = &IpFilter{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	deny, err := ParsePrefixes([]string{"127.0.0.0/8", "::1"})
+	require.NoError(t, err)
+	server.IpFilter.Set(nil, deny)
+	resp, err := http.Get(ts.URL + "/socket.io/?EIO=3&transport=websocket")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	server.IpFilter.Set(nil, nil)
+	c, err := Dial(context.Background(), ts.URL)
+	require.NoError(t, err)
+	c.Close()
+}
@@ -0,0 +1,76 @@
+package gosocketio
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrorIpNotAllowed = errors.New("Ip not allowed")
+)
+
+/**
+Allow and deny lists of client networks, zero value allows every client
+Denied networks win over allowed ones, non empty allow list lets only its
+networks in. Lists may be replaced by Set at any time, new connections are
+checked against the current ones
+*/
+type IpFilter struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+	lock  sync.RWMutex
+}
+
+/**
+Parse list of networks in CIDR notation, single addresses are taken as
+networks of one address
+*/
+func ParsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, err
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+/**
+Replace allow and deny lists
+*/
+func (f *IpFilter) Set(allow, deny []netip.Prefix) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.allow, f.deny = allow, deny
+}
+
+/**
+Check if client ip is allowed, unknown ip is allowed only if allow list
+is empty
+*/
+func (f *IpFilter) Allowed(ip netip.Addr) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	ip = ip.Unmap()
+	if ip.IsValid() && inNetworks(ip, f.deny) {
+		return false
+	}
+	return len(f.allow) == 0 || (ip.IsValid() && inNetworks(ip, f.allow))
+}
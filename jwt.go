@@ -0,0 +1,232 @@
+package gosocketio
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrorTokenMissing     = errors.New("Token missing")
+	ErrorTokenMalformed   = errors.New("Token malformed")
+	ErrorTokenAlgorithm   = errors.New("Token algorithm not allowed")
+	ErrorTokenSignature   = errors.New("Token signature invalid")
+	ErrorTokenExpired     = errors.New("Token expired")
+	ErrorTokenNotValidYet = errors.New("Token not valid yet")
+	ErrorTokenIssuer      = errors.New("Token issuer not allowed")
+	ErrorTokenAudience    = errors.New("Token audience not allowed")
+)
+
+/**
+Claims of verified JWT, attached to channel as its identity
+*/
+type JWTClaims map[string]interface{}
+
+/**
+Get sub claim, empty if there is none
+*/
+func (c JWTClaims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+/**
+Get exp claim, zero time if there is none
+*/
+func (c JWTClaims) ExpiresAt() time.Time {
+	return c.time("exp")
+}
+
+/**
+Get numeric date claim as time, zero time if there is none
+*/
+func (c JWTClaims) time(name string) time.Time {
+	seconds, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+/**
+JWT authentication of server, verifies HS256 or RS256 signature, exp, nbf,
+issuer and audience of token, and attaches its claims to the channel
+Use Authorize as Server.Authorize for tokens of Authorization header or
+query, or AuthorizeConnect as Server.AuthorizeConnect for tokens of auth
+payload. Refused clients get CONNECT_ERROR with error message.
+Set Server.DisconnectOnExpiry to disconnect sockets when tokens expire
+*/
+type JWTAuth struct {
+	//key of HS256 tokens, nil does not allow HS256
+	Secret []byte
+	//key of RS256 tokens, nil does not allow RS256
+	PublicKey *rsa.PublicKey
+
+	//required iss and aud claims, empty ones are not checked
+	Issuer   string
+	Audience string
+	//clock skew allowed checking exp and nbf
+	Leeway time.Duration
+
+	//query param and field of auth payload with token, "token" if empty
+	QueryParam string
+	AuthField  string
+}
+
+/**
+Verify token and get its claims
+*/
+func (a *JWTAuth) Verify(token string) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrorTokenMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrorTokenMalformed
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	switch {
+	case header.Alg == "HS256" && a.Secret != nil:
+		mac := hmac.New(sha256.New, a.Secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, ErrorTokenSignature
+		}
+	case header.Alg == "RS256" && a.PublicKey != nil:
+		sum := sha256.Sum256(signed)
+		if rsa.VerifyPKCS1v15(a.PublicKey, crypto.SHA256, sum[:], signature) != nil {
+			return nil, ErrorTokenSignature
+		}
+	default:
+		return nil, ErrorTokenAlgorithm
+	}
+
+	claims := JWTClaims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, a.validate(claims)
+}
+
+/**
+Check time, issuer and audience claims
+*/
+func (a *JWTAuth) validate(claims JWTClaims) error {
+	now := time.Now()
+	if exp := claims.ExpiresAt(); !exp.IsZero() && !now.Before(exp.Add(a.Leeway)) {
+		return ErrorTokenExpired
+	}
+	if nbf := claims.time("nbf"); !nbf.IsZero() && now.Add(a.Leeway).Before(nbf) {
+		return ErrorTokenNotValidYet
+	}
+	if a.Issuer != "" && claims["iss"] != a.Issuer {
+		return ErrorTokenIssuer
+	}
+	if a.Audience != "" && !hasAudience(claims["aud"], a.Audience) {
+		return ErrorTokenAudience
+	}
+	return nil
+}
+
+/**
+Authorization of handshake request with token of Authorization header or
+query param, see Server.Authorize
+*/
+func (a *JWTAuth) Authorize(r *http.Request) (interface{}, error) {
+	token := bearerToken(r.Header)
+	if token == "" {
+		token = r.URL.Query().Get(orDefault(a.QueryParam, "token"))
+	}
+	return a.authorize(token)
+}
+
+/**
+Authorization of namespace connect with token of auth payload, or of
+Authorization header if payload has none, see Server.AuthorizeConnect
+*/
+func (a *JWTAuth) AuthorizeConnect(c *Channel, auth json.RawMessage) (interface{}, error) {
+	var payload map[string]interface{}
+	json.Unmarshal(auth, &payload)
+
+	token, _ := payload[orDefault(a.AuthField, "token")].(string)
+	if token == "" {
+		token = bearerToken(c.RequestHeader())
+	}
+	return a.authorize(token)
+}
+
+/**
+Verify token, errors are returned as *ConnectError to refuse namespace
+*/
+func (a *JWTAuth) authorize(token string) (interface{}, error) {
+	if token == "" {
+		return nil, &ConnectError{Message: ErrorTokenMissing.Error()}
+	}
+	claims, err := a.Verify(token)
+	if err != nil {
+		return nil, &ConnectError{Message: err.Error()}
+	}
+	return claims, nil
+}
+
+/**
+Decode base64url encoded json segment of token
+*/
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil || json.Unmarshal(data, v) != nil {
+		return ErrorTokenMalformed
+	}
+	return nil
+}
+
+/**
+Check if aud claim, string or array of strings, contains audience
+*/
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, value := range aud {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/**
+Get token of "Authorization: Bearer" header, empty if there is none
+*/
+func bearerToken(header http.Header) string {
+	value := header.Get("Authorization")
+	if len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+		return strings.TrimSpace(value[7:])
+	}
+	return ""
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,134 @@
+package gosocketio
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signToken(t *testing.T, alg string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		require.NoError(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerify(t *testing.T) {
+	secret := []byte("secret")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	a := &JWTAuth{Secret: secret, PublicKey: &key.PublicKey, Issuer: "auth", Audience: "chat"}
+
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"sub": "alice", "iss": "auth", "aud": []string{"chat"}, "exp": now + 60}
+	claims, err := a.Verify(signToken(t, "HS256", secret, valid))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Subject())
+	assert.Equal(t, now+60, claims.ExpiresAt().Unix())
+
+	_, err = a.Verify(signToken(t, "RS256", key, valid))
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		token string
+		err   error
+	}{
+		{"abc", ErrorTokenMalformed},
+		{signToken(t, "HS256", []byte("other"), valid), ErrorTokenSignature},
+		{signToken(t, "none", nil, valid), ErrorTokenAlgorithm},
+		{signToken(t, "HS256", secret, map[string]interface{}{"iss": "auth", "aud": "chat", "exp": now - 1}), ErrorTokenExpired},
+		{signToken(t, "HS256", secret, map[string]interface{}{"iss": "auth", "aud": "chat", "nbf": now + 60}), ErrorTokenNotValidYet},
+		{signToken(t, "HS256", secret, map[string]interface{}{"iss": "other", "aud": "chat"}), ErrorTokenIssuer},
+		{signToken(t, "HS256", secret, map[string]interface{}{"iss": "auth", "aud": "other"}), ErrorTokenAudience},
+	} {
+		_, err := a.Verify(tc.token)
+		assert.Equal(t, tc.err, err, tc.token)
+	}
+}
+
+func TestJWTAuthorize(t *testing.T) {
+	secret := []byte("secret")
+	a := &JWTAuth{Secret: secret}
+	identities := make(chan interface{}, 1)
+	disconnected := make(chan string, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.Authorize = a.Authorize
+	server.DisconnectOnExpiry = true
+	server.On(OnConnection, func(h *Channel) {
+		identities <- h.Identity()
+	})
+	server.On(OnDisconnection, func(h *Channel) {
+		disconnected <- h.DisconnectReason()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	expired := signToken(t, "HS256", secret, map[string]interface{}{"exp": time.Now().Unix() - 1})
+	_, err := Dial(context.Background(), ts.URL, WithQuery(url.Values{"token": {expired}}))
+	require.IsType(t, &ConnectError{}, err)
+	assert.Equal(t, ErrorTokenExpired.Error(), err.(*ConnectError).Message)
+
+	token := signToken(t, "HS256", secret, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(1500 * time.Millisecond).Unix(),
+	})
+	c, err := Dial(context.Background(), ts.URL, WithQuery(url.Values{"token": {token}}))
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, "alice", (<-identities).(JWTClaims).Subject())
+
+	select {
+	case reason := <-disconnected:
+		assert.Equal(t, ReasonTokenExpired, reason)
+	case <-time.After(5 * time.Second):
+		t.Fatal("socket was not disconnected on expiry")
+	}
+}
+
+func TestJWTAuthorizeConnect(t *testing.T) {
+	secret := []byte("secret")
+	a := &JWTAuth{Secret: secret}
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.AuthorizeConnect = a.AuthorizeConnect
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	_, err := Dial(context.Background(), ts.URL, WithAuth(map[string]string{"token": "abc"}))
+	require.IsType(t, &ConnectError{}, err)
+	assert.Equal(t, ErrorTokenMalformed.Error(), err.(*ConnectError).Message)
+
+	token := signToken(t, "HS256", secret, map[string]interface{}{"sub": "bob"})
+	c, err := Dial(context.Background(), ts.URL, WithAuth(map[string]string{"token": token}))
+	require.NoError(t, err)
+	defer c.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int64(1), server.AmountOfSids())
+}
@@ -0,0 +1,151 @@
+package gosocketio
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+var (
+	ErrorTooManyConnections       = errors.New("Too many connections")
+	ErrorTooManyConnectionsFromIp = errors.New("Too many connections from ip")
+	ErrorConnectionRate           = errors.New("Connection rate exceeded")
+)
+
+/**
+Connection limits of server, checked before authorization and upgrade
+Request over MaxConnections gets 503, over MaxConnectionsPerIp or Rate
+gets 429. Zero values mean no limit
+*/
+type ConnectionLimits struct {
+	//connections of the server in total
+	MaxConnections int
+	//connections of one client ip, resolved as Channel.Ip
+	MaxConnectionsPerIp int
+	//new connections per second and how many of them may come at once,
+	//burst defaults to rate
+	Rate  float64
+	Burst int
+
+	//called with every rejected request, its client ip and one of
+	//ErrorTooManyConnections, ErrorTooManyConnectionsFromIp or
+	//ErrorConnectionRate
+	OnShed func(r *http.Request, ip netip.Addr, err error)
+}
+
+/**
+Token bucket, refilled by rate tokens per second up to burst
+*/
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+/**
+Take one token if there is one
+*/
+func (b *tokenBucket) take(rate float64, burst int, now time.Time) bool {
+	max := float64(burst)
+	if max < 1 {
+		max = rate
+	}
+	if max < 1 {
+		max = 1
+	}
+
+	if b.last.IsZero() {
+		b.tokens = max
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * rate
+	}
+	if b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+/**
+Counters of connections admitted by limits
+*/
+type connLimiter struct {
+	total int
+	perIp map[netip.Addr]int
+	rate  tokenBucket
+	lock  sync.Mutex
+}
+
+/**
+Admit one more connection of ip, returns error if it is over limits
+*/
+func (l *connLimiter) acquire(limits *ConnectionLimits, ip netip.Addr) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if limits.MaxConnections > 0 && l.total >= limits.MaxConnections {
+		return ErrorTooManyConnections
+	}
+	if limits.MaxConnectionsPerIp > 0 && ip.IsValid() &&
+		l.perIp[ip] >= limits.MaxConnectionsPerIp {
+		return ErrorTooManyConnectionsFromIp
+	}
+	if limits.Rate > 0 && !l.rate.take(limits.Rate, limits.Burst, time.Now()) {
+		return ErrorConnectionRate
+	}
+
+	l.total++
+	if l.perIp == nil {
+		l.perIp = make(map[netip.Addr]int)
+	}
+	l.perIp[ip]++
+	return nil
+}
+
+/**
+Release connection of ip admitted before
+*/
+func (l *connLimiter) release(ip netip.Addr) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.total--
+	if l.perIp[ip]--; l.perIp[ip] <= 0 {
+		delete(l.perIp, ip)
+	}
+}
+
+/**
+Check limits for request, rejected request is answered and reported to
+shedding hook. Returns release func of admitted connection, nil if rejected
+*/
+func (s *Server) admit(w http.ResponseWriter, r *http.Request, ip netip.Addr) func() {
+	limits := s.Limits
+	if limits == nil {
+		return func() {}
+	}
+
+	err := s.limiter.acquire(limits, ip)
+	if err == nil {
+		var once sync.Once
+		return func() {
+			once.Do(func() { s.limiter.release(ip) })
+		}
+	}
+
+	if limits.OnShed != nil {
+		limits.OnShed(r, ip, err)
+	}
+	status := http.StatusTooManyRequests
+	if err == ErrorTooManyConnections {
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, err.Error(), status)
+	return nil
+}
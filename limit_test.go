@@ -0,0 +1,82 @@
+package gosocketio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := tokenBucket{}
+	now := time.Now()
+	assert.True(t, b.take(2, 2, now))
+	assert.True(t, b.take(2, 2, now))
+	assert.False(t, b.take(2, 2, now))
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, b.take(2, 2, now))
+	assert.False(t, b.take(2, 2, now))
+
+	now = now.Add(time.Hour)
+	assert.True(t, b.take(2, 2, now))
+	assert.True(t, b.take(2, 2, now))
+	assert.False(t, b.take(2, 2, now))
+}
+
+func TestConnLimiter(t *testing.T) {
+	limits := &ConnectionLimits{MaxConnections: 3, MaxConnectionsPerIp: 2}
+	first := netip.MustParseAddr("10.0.0.1")
+	second := netip.MustParseAddr("10.0.0.2")
+
+	l := connLimiter{}
+	require.NoError(t, l.acquire(limits, first))
+	require.NoError(t, l.acquire(limits, first))
+	assert.Equal(t, ErrorTooManyConnectionsFromIp, l.acquire(limits, first))
+	require.NoError(t, l.acquire(limits, second))
+	assert.Equal(t, ErrorTooManyConnections, l.acquire(limits, second))
+
+	l.release(first)
+	require.NoError(t, l.acquire(limits, second))
+	l.release(first)
+	assert.Equal(t, ErrorTooManyConnectionsFromIp, l.acquire(limits, second))
+}
+
+func TestServerLimits(t *testing.T) {
+	shed := make(chan error, 1)
+	disconnected := make(chan struct{}, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.Limits = &ConnectionLimits{
+		MaxConnectionsPerIp: 1,
+		OnShed: func(r *http.Request, ip netip.Addr, err error) {
+			shed <- err
+		},
+	}
+	server.On(OnDisconnection, func(h *Channel) {
+		disconnected <- struct{}{}
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	c, err := Dial(context.Background(), ts.URL)
+	require.NoError(t, err)
+
+	resp, err := http.Get(ts.URL + "/socket.io/?EIO=3&transport=websocket")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, ErrorTooManyConnectionsFromIp, <-shed)
+
+	c.Close()
+	<-disconnected
+
+	c, err = Dial(context.Background(), ts.URL)
+	require.NoError(t, err)
+	c.Close()
+}
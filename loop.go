@@ -1,9 +1,11 @@
 package gosocketio
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/netip"
 	"sync"
 	"time"
 	"github.com/guhan121/golang-socketio/transport"
@@ -15,6 +17,23 @@ const (
 	queueBufferSize = 500
 )
 
+const (
+	//reasons of disconnection, as named by socket.io
+	ReasonServerDisconnect          = "io server disconnect"
+	ReasonClientDisconnect          = "io client disconnect"
+	ReasonServerNamespaceDisconnect = "server namespace disconnect"
+	ReasonClientNamespaceDisconnect = "client namespace disconnect"
+	ReasonTransportClose            = "transport close"
+	ReasonTransportError            = "transport error"
+
+	//reasons of closing peer which breaks payload limits
+	ReasonPayloadTooLarge    = "payload too large"
+	ReasonTooManyAttachments = "too many attachments"
+
+	//reason of closing slow consumer by OverflowDisconnect policy
+	ReasonQueueOverflow = "queue overflow"
+)
+
 var (
 	ErrorWrongHeader = errors.New("Wrong header")
 )
@@ -27,6 +46,22 @@ type Header struct {
 	Upgrades     []string `json:"upgrades"`
 	PingInterval int      `json:"pingInterval"`
 	PingTimeout  int      `json:"pingTimeout"`
+	MaxPayload   int64    `json:"maxPayload,omitempty"`
+}
+
+/**
+Encoded packet waiting in outgoing queue: text frame and its binary frames
+Control packets (open, ping, pong, connect) are not subject to overflow policy
+Volatile packets are dropped when connection is not writable right now
+*/
+type packet struct {
+	text     string
+	binary   [][]byte
+	control  bool
+	volatile bool
+
+	//closed by outLoop when packet is written, nil if nobody waits for it
+	written chan struct{}
 }
 
 /**
@@ -41,40 +76,122 @@ ping is automatic
 type Channel struct {
 	conn transport.Connection
 
-	out    chan byte //部分数据放到这，然后在outloop函数中发送文本格式数据发送出去
-	Header Header
+	out         *outQueue
+	overflooded *overfloodRegistry
+	Header      Header
 
 	alive     bool
 	aliveLock sync.Mutex
+	state     State
+	//reason of the last disconnection, empty while connected
+	reason string
 
 	ack ackProcessor
 
+	//client only, keeps packets sent while offline, nil if disabled
+	offline *offlineBuffer
+
+	//socket.io namespace of the channel, empty for default namespace
+	namespace string
+	//client only, json auth payload sent with namespace connect packet
+	auth string
+	//client only, receives result of handshake and namespace connect
+	handshake chan error
+
+	stats trafficStats
+	pings pingTracker
+
+	//client manager socket only, channel of shared engine.io connection
+	engine *Channel
+	//handlers of the channel owner, used to close channel from inside
+	handlers *methods
+
 	server        *Server
-	ip            string
+	ip            netip.Addr
 	requestHeader http.Header
+	//server only, identity given by authorization hook, guarded by aliveLock
+	identity interface{}
+	//server only, releases connection admitted by limits
+	release func()
+	//server only, buckets of incoming events
+	events eventLimiter
+	//max binary attachments of incoming packet, zero means no limit
+	maxAttachments int
+	//server only, ask for fresh auth payload and disconnect channel when
+	//identity expires, guarded by aliveLock
+	refresh *time.Timer
+	expiry  *time.Timer
 	msgChannel    chan *protocol.Message
+
+	//closed when current connection is closed, stops its loops
+	done chan struct{}
+}
+
+/**
+Current connection of channel with everything its loops work with, taken
+once when loops start, so loops of replaced connection never see new one
+*/
+type loopState struct {
+	conn      transport.Connection
+	out       *outQueue
+	msgs      chan *protocol.Message
+	handshake chan error
+	done      chan struct{}
+}
+
+/**
+Get current connection for starting its loops
+*/
+func (c *Channel) loops() loopState {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+
+	return loopState{c.conn, c.out, c.msgChannel, c.handshake, c.done}
 }
 
 /**
 create channel, map, and set active
 */
-func (c *Channel) initChannel() {
-	//TODO: queueBufferSize from constant to server or client variable
-	c.out = make(chan byte, queueBufferSize)
-	c.ack.resultWaiters = make(map[int](chan string))
+func (c *Channel) initChannel(m *methods, config QueueConfig, overflooded *overfloodRegistry) {
+	config = config.withDefaults()
+	c.handlers = m
+	c.overflooded = overflooded
+	if c.out != nil {
+		c.stats.retireQueue(c.out)
+	}
+	c.out = newOutQueue(config, func(high bool) {
+		if high {
+			overflooded.add(c)
+		} else {
+			overflooded.remove(c)
+		}
+		if config.OnWatermark != nil {
+			config.OnWatermark(c, high)
+		}
+	})
+	if c.ack.resultWaiters == nil {
+		c.ack.resultWaiters = make(map[int]*ackWaiter)
+	}
+	c.pings.reset()
 	c.alive = true
+	c.reason = ""
 	c.msgChannel = make(chan *protocol.Message)
+	c.done = make(chan struct{})
 }
 
 /**
 Get id of current socket connection
 */
 func (c *Channel) Id() string {
+	if c.engine != nil {
+		return c.engine.Id()
+	}
 	return c.Header.Sid
 }
 
 func (c *Channel) GetConnect() transport.Connection {
-	return c.conn
+	conn, _ := c.current()
+	return conn
 }
 
 /**
@@ -88,141 +205,372 @@ func (c *Channel) IsAlive() bool {
 }
 
 /**
-Close channel
+Get connection and outgoing queue, they are replaced when client reconnects
+Manager sockets use connection of the manager
+*/
+func (c *Channel) current() (transport.Connection, *outQueue) {
+	if c.engine != nil {
+		return c.engine.current()
+	}
+
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+
+	return c.conn, c.out
+}
+
+/**
+Put packet to outgoing queue, applying overflow policy if queue is full
+With disconnect policy the connection is closed on overflow
 */
-func closeChannel(c *Channel, m *methods, args ...interface{}) error {
+func (c *Channel) enqueue(p *packet) error {
+	if c.offline != nil && !p.control {
+		if buffered, err := c.offline.push(p); buffered {
+			return err
+		}
+	}
+
+	_, out := c.current()
+	if out == nil {
+		return ErrorChannelClosed
+	}
+
+	err := out.push(p)
+	if err == ErrorSocketOverflood {
+		//slow consumer, close connection shared by manager sockets too
+		owner := c
+		if c.engine != nil {
+			owner = c.engine
+		}
+		closeChannel(owner, owner.handlers, ReasonQueueOverflow)
+	}
+	return err
+}
+
+/**
+Put control packet without arguments, such as ping or pong, to outgoing queue
+*/
+func (c *Channel) enqueueText(text string) error {
+	return c.enqueue(&packet{text: text, control: true})
+}
+
+/**
+Get reason of the last disconnection, such as ReasonServerDisconnect,
+empty while channel is connected
+*/
+func (c *Channel) DisconnectReason() string {
 	c.aliveLock.Lock()
 	defer c.aliveLock.Unlock()
-	if !c.alive {
+
+	return c.reason
+}
+
+/**
+Keep reason of disconnection which is about to happen, so close caused by peer
+reacting to it reports the same reason
+*/
+func (c *Channel) closing(reason string) {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+
+	if c.alive && c.reason == "" {
+		c.reason = reason
+	}
+}
+
+/**
+Close channel with given reason of disconnection
+*/
+func closeChannel(c *Channel, m *methods, reason string) error {
+	return closeChannelOf(c, m, nil, reason)
+}
+
+/**
+Close channel if connection identified by done is still the current one,
+so loops of connection replaced by reconnect do not close the new one
+nil done closes current connection
+*/
+func closeChannelOf(c *Channel, m *methods, done chan struct{}, reason string) error {
+	c.aliveLock.Lock()
+	if !c.alive || (done != nil && done != c.done) {
 		//already closed
+		c.aliveLock.Unlock()
 		return nil
 	}
 
-	c.conn.Close()
 	c.alive = false
+	if c.reason == "" {
+		c.reason = reason
+	}
+	conn, out := c.conn, c.out
+	close(c.done)
+	handshake := c.handshake
+	from := c.state
+	c.aliveLock.Unlock()
+
+	handshakeDone(handshake, ErrorHandshakeClosed)
+
+	if c.offline != nil {
+		c.offline.goOffline()
+	}
+	conn.Close()
+
+	//clean and stop outloop
+	out.close()
+	c.overflooded.remove(c)
 
-	//clean outloop
-	for len(c.out) > 0 {
-		<-c.out
+	resend := c.ack.disconnected(c.offline)
+	if c.offline != nil {
+		c.offline.requeue(resend)
 	}
-	rs := []byte(protocol.CloseMessage)
-	for _, r := range rs {
-		c.out <- r
+
+	to := StateClosed
+	var reconnect func()
+	if c.server == nil && (from == StateConnecting || from == StateReconnecting) {
+		//connect or reconnect in progress decides what comes next
+		to = from
+	} else if from == StateOpen && m.reconnector != nil {
+		reconnect = m.reconnector(c)
+	}
+	if reconnect != nil {
+		to = StateReconnecting
 	}
-	m.callLoopEvent(c, OnDisconnection)
 
-	overfloodedLock.Lock()
-	delete(overflooded, c)
-	overfloodedLock.Unlock()
+	m.transition(c, to, from)
+	if reconnect != nil {
+		go reconnect()
+	}
 	return nil
 }
 
-//incoming messages loop, puts incoming messages to In channel
-func procLoop(c *Channel, m *methods) error {
+/**
+Send namespace disconnect packets, optionally followed by engine.io close
+packet, then wait until they are written together with everything queued
+before them
+Returns at once if channel is closed already
+*/
+func (c *Channel) flushClose(ctx context.Context, closeEngine bool, namespaces ...string) error {
+	var texts []string
+	for _, namespace := range namespaces {
+		texts = append(texts, protocol.MustEncode(&protocol.Message{
+			Type:      protocol.MessageTypeDisconnect,
+			Namespace: namespace,
+		}))
+	}
+	if closeEngine {
+		texts = append(texts, protocol.CloseMessage)
+	}
+	return c.flushText(ctx, texts...)
+}
 
+/**
+Send control packets and wait until they are written together with
+everything queued before them
+Returns at once if channel is closed already
+*/
+func (c *Channel) flushText(ctx context.Context, texts ...string) error {
+	c.aliveLock.Lock()
+	alive, done := c.alive, c.done
+	c.aliveLock.Unlock()
+	if !alive {
+		return nil
+	}
+
+	written := make(chan struct{})
+	for i, text := range texts {
+		p := &packet{text: text, control: true}
+		if i == len(texts)-1 {
+			p.written = written
+		}
+		if err := c.enqueue(p); err != nil {
+			return err
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	select {
+	case <-written:
+		return nil
+	case <-done:
+		select {
+		case <-written:
+			return nil
+		default:
+			return ErrorChannelClosed
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/**
+Report result of client handshake, only the first result is kept
+*/
+func handshakeDone(handshake chan error, err error) {
+	if handshake == nil {
+		return
+	}
+	select {
+	case handshake <- err:
+	default:
+	}
+}
+
+//incoming messages loop, puts incoming messages to In channel
+func procLoop(c *Channel, m *methods, ls loopState) error {
 	for {
-		msg, ok := <-c.msgChannel
-		if ok {
+		select {
+		case msg := <-ls.msgs:
 			m.processIncomingMessage(c, msg)
-		} else {
-			break
+		case <-ls.done:
+			return nil
 		}
 	}
-	return nil
 }
 
 //incoming messages loop, puts incoming messages to In channel
-func inLoop(c *Channel, m *methods) error {
+func inLoop(c *Channel, m *methods, ls loopState) error {
+	conn, done, handshake := ls.conn, ls.done, ls.handshake
 
 	for {
-		pkg, messageType, err := c.conn.GetMessage()
+		pkg, messageType, err := conn.GetMessage()
 		//fmt.Println("read --- pkg_head", messageType, string(pkg))
+		if err == transport.ErrorMessageTooBig {
+			return closeChannelOf(c, m, done, ReasonPayloadTooLarge)
+		}
 		if err != nil {
 			//fmt.Println(",,,,,,,",err)
-			return closeChannel(c, m, err)
+			return closeChannelOf(c, m, done, ReasonTransportClose)
 		}
+		c.stats.received(len(pkg), messageType == websocket.BinaryMessage)
 		if messageType != websocket.BinaryMessage {
 			msg, err := protocol.Decode(pkg)
 			if err != nil {
-				closeChannel(c, m, protocol.ErrorWrongPacket)
+				closeChannelOf(c, m, done, ReasonTransportError)
 				return err
 			}
 
+			if c.maxAttachments > 0 && msg.Num > c.maxAttachments {
+				return closeChannelOf(c, m, done, ReasonTooManyAttachments)
+			}
 			for i := 0; i < msg.Num; i++ {
-				pkg1, messageType1, err := c.conn.GetMessage()
+				pkg1, messageType1, err := conn.GetMessage()
+				if err == transport.ErrorMessageTooBig {
+					return closeChannelOf(c, m, done, ReasonPayloadTooLarge)
+				}
 				if err != nil || messageType1 != websocket.BinaryMessage {
 					//fmt.Println("---------",err)
-					return closeChannel(c, m, err)
+					return closeChannelOf(c, m, done, ReasonTransportError)
 				}
+				c.stats.received(len(pkg1), true)
 				//pkg1_type := pkg1[0]
 				//fmt.Println("read --- pkg_byte", messageType1, i, pkg1_type, "-->",pkg1)
 				msg.Data = append(msg.Data, pkg1...)
 			}
 
+			if msg.Type == protocol.MessageTypeEmpty && msg.Namespace == c.namespace &&
+				c.server == nil {
+				//namespace connected, send what was emitted while offline
+				if c.offline != nil {
+					_, out := c.current()
+					c.offline.flush(out)
+				}
+				m.transition(c, StateOpen, StateConnecting, StateReconnecting)
+				handshakeDone(handshake, nil)
+			}
+			if msg.Type == protocol.MessageTypeEmpty && msg.Namespace == c.namespace &&
+				c.server != nil {
+				c.server.connectNamespace(c, msg.Args)
+			}
+			if msg.Type == protocol.MessageTypeConnectError && msg.Namespace == c.namespace {
+				handshakeDone(handshake, newConnectError(msg.Args))
+			}
+
+			if msg.Type == protocol.MessageTypeClose {
+				return closeChannelOf(c, m, done, ReasonTransportClose)
+			}
+			if msg.Type == protocol.MessageTypeDisconnect && msg.Namespace == c.namespace &&
+				m.onPacket == nil {
+				//peer left the namespace, manager gets it with other packets
+				if c.server != nil {
+					return closeChannelOf(c, m, done, ReasonClientNamespaceDisconnect)
+				}
+				return closeChannelOf(c, m, done, ReasonServerDisconnect)
+			}
+
 			switch msg.Type {
 			case protocol.MessageTypeOpen:
 				if err := json.Unmarshal([]byte(msg.Source[1:]), &c.Header); err != nil {
-					closeChannel(c, m, ErrorWrongHeader)
+					closeChannelOf(c, m, done, ReasonTransportError)
 				}
-				if c.server == nil {
-					//客户端
-					//fmt.Print(c.conn.PingParams())
+				if auth := c.authPayload(); c.server == nil && (c.namespace != "" || auth != "") {
+					//客户端, connect to namespace or send auth payload
+					c.enqueueText(protocol.MustEncode(&protocol.Message{
+						Type:      protocol.MessageTypeEmpty,
+						Namespace: c.namespace,
+						Args:      auth,
+					}))
 				}
-				m.callLoopEvent(c, OnConnection)
 			case protocol.MessageTypePing:
-
-				rs := []byte(protocol.PongMessage)
-				for _, r := range rs {
-					c.out <- r
-				}
+				c.enqueueText(protocol.PongMessage)
 			case protocol.MessageTypePong:
+				c.onPong()
 			default:
-				c.msgChannel <- msg
+				select {
+				case ls.msgs <- msg:
+				case <-done:
+					return nil
+				}
 				//go m.processIncomingMessage(c, msg)
 			}
 		} else {
 			return errors.New("Binary must read after text!")
 		}
 	}
-	return nil
-}
-
-var overflooded map[*Channel]struct{} = make(map[*Channel]struct{})
-var overfloodedLock sync.Mutex
-
-func AmountOfOverflooded() int64 {
-	overfloodedLock.Lock()
-	defer overfloodedLock.Unlock()
-
-	return int64(len(overflooded))
 }
 
 /**
 outgoing messages loop, sends messages from channel to socket
 */
-func outLoop(c *Channel, m *methods) error {
+func outLoop(c *Channel, m *methods, ls loopState) error {
+	conn, out, done := ls.conn, ls.out, ls.done
+
 	for {
-		outBufferLen := len(c.out)
-		if outBufferLen >= queueBufferSize-1 {
-			return closeChannel(c, m, ErrorSocketOverflood)
-		} else if outBufferLen > int(queueBufferSize/2) {
-			overfloodedLock.Lock()
-			overflooded[c] = struct{}{}
-			overfloodedLock.Unlock()
-		} else {
-			overfloodedLock.Lock()
-			delete(overflooded, c)
-			overfloodedLock.Unlock()
+		p, ok := out.pop()
+		if !ok {
+			return nil
 		}
 
-		msg := <-c.out
-		if string(msg) == protocol.CloseMessage {
-			return nil
+		ping := p.control && p.text == protocol.PingMessage
+		var pingAt time.Time
+		if ping {
+			pingAt = c.pings.pingSent()
+		}
+		if err := writePacket(conn, p); err != nil {
+			if ping {
+				c.pings.pingFailed(pingAt)
+			}
+			return closeChannelOf(c, m, done, ReasonTransportError)
 		}
+		c.stats.sent(p)
+		if p.written != nil {
+			close(p.written)
+		}
+	}
+}
 
-		err := c.conn.WriteMessage(string(msg))
-		if err != nil {
-			return closeChannel(c, m, err)
+/**
+Write text frame of the packet followed by its binary frames
+*/
+func writePacket(conn transport.Connection, p *packet) error {
+	if err := conn.WriteMessage(p.text); err != nil {
+		return err
+	}
+	for _, b := range p.binary {
+		if err := conn.WriteBytes(b); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -231,17 +579,17 @@ func outLoop(c *Channel, m *methods) error {
 /**
 Pinger sends ping messages for keeping connection alive
 */
-func pinger(c *Channel) {
+func pinger(c *Channel, ls loopState) {
+	conn, done := ls.conn, ls.done
+
 	for {
-		interval, _ := c.conn.PingParams()
-		time.Sleep(interval)
-		if !c.IsAlive() {
+		interval, _ := conn.PingParams()
+		select {
+		case <-time.After(interval):
+		case <-done:
 			return
 		}
 
-		rs := []byte(protocol.PingMessage)
-		for _, r := range rs {
-			c.out <- r
-		}
+		c.enqueueText(protocol.PingMessage)
 	}
 }
@@ -0,0 +1,382 @@
+package gosocketio
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/guhan121/golang-socketio/protocol"
+)
+
+/**
+Client connection shared by sockets of several namespaces
+
+Manager owns engine.io connection with its heartbeats, reconnection, outgoing
+queue and overflood registry, every namespace gets its own Socket with own
+handlers, ack ids and connect/disconnect events
+*/
+type Manager struct {
+	engine  *Client
+	offline *OfflineConfig
+
+	sockets     map[string]*Socket
+	socketsLock sync.Mutex
+}
+
+/**
+Socket of one namespace multiplexed over connection of manager
+Handlers get its Channel, which emits to the namespace of the socket
+*/
+type Socket struct {
+	methods
+	Channel
+
+	manager *Manager
+	//namespace connect was asked and socket is not closed, guarded by
+	//socketsLock of manager, connect is sent again after reconnection
+	active bool
+}
+
+/**
+Connect to socket.io server and return manager, use Socket to join namespaces
+Options are the ones of Dial, except namespace and auth which are given to
+each socket, handlers get manager events like reconnect_attempt
+*/
+func DialManager(ctx context.Context, rawurl string, opts ...DialOption) (*Manager, error) {
+	c, err := newDialClient(rawurl, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		engine:  c,
+		offline: c.Offline,
+		sockets: make(map[string]*Socket),
+	}
+	c.namespace, c.auth = "", ""
+	c.Offline, c.offline = nil, nil
+	c.onConnection = m.onOpen
+	c.onDisconnection = m.onClose
+	c.onPacket = m.onPacket
+
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+/**
+Get socket of given namespace, it is created if not exist yet
+Register handlers and call Connect to join the namespace
+*/
+func (m *Manager) Socket(namespace string) *Socket {
+	if namespace == "/" {
+		namespace = ""
+	}
+
+	m.socketsLock.Lock()
+	defer m.socketsLock.Unlock()
+
+	if s, ok := m.sockets[namespace]; ok {
+		return s
+	}
+
+	s := &Socket{manager: m}
+	s.state = StateClosed
+	s.initMethods()
+	s.namespace = namespace
+	s.engine = &m.engine.Channel
+	s.ack.resultWaiters = make(map[int]*ackWaiter)
+	if m.offline != nil {
+		s.offline = newOfflineBuffer(*m.offline)
+	}
+	m.sockets[namespace] = s
+	return s
+}
+
+/**
+Add handler of manager event, such as reconnect or transport events
+*/
+func (m *Manager) On(method string, f interface{}) error {
+	return m.engine.On(method, f)
+}
+
+/**
+Get engine.io session id of current connection
+*/
+func (m *Manager) Id() string {
+	return m.engine.Id()
+}
+
+/**
+Checks that engine.io connection is alive
+*/
+func (m *Manager) IsAlive() bool {
+	return m.engine.IsAlive()
+}
+
+/**
+Get engine.io name of transport used by current connection
+*/
+func (m *Manager) Transport() string {
+	return m.engine.Transport()
+}
+
+/**
+Get snapshot of connection counters
+*/
+func (m *Manager) Stats() Stats {
+	return m.engine.Stats()
+}
+
+/**
+Get amount of manager channels with outgoing queue over high watermark
+*/
+func (m *Manager) AmountOfOverflooded() int64 {
+	return m.engine.AmountOfOverflooded()
+}
+
+/**
+Get manager channel with queue depth and time spent over high watermark,
+empty if it is not overflooded
+*/
+func (m *Manager) ListOverflooded() []OverfloodedChannel {
+	return m.engine.ListOverflooded()
+}
+
+/**
+Close connection, sockets of all namespaces get disconnected
+*/
+func (m *Manager) Close() {
+	m.engine.Close()
+}
+
+/**
+Close connection gracefully: leave namespaces of connected sockets, close
+engine.io session and write everything queued before, then close connection
+Connection is closed anyway when ctx is done, its error is returned then
+*/
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.socketsLock.Lock()
+	var namespaces []string
+	for _, s := range m.sockets {
+		if s.IsAlive() {
+			namespaces = append(namespaces, s.namespace)
+		}
+	}
+	m.socketsLock.Unlock()
+
+	return m.engine.shutdown(ctx, namespaces...)
+}
+
+/**
+Get socket of namespace, nil if not exist
+*/
+func (m *Manager) socket(namespace string) *Socket {
+	m.socketsLock.Lock()
+	defer m.socketsLock.Unlock()
+
+	return m.sockets[namespace]
+}
+
+/**
+Engine.io connection opened, connect active sockets again
+*/
+func (m *Manager) onOpen(c *Channel) {
+	m.socketsLock.Lock()
+	defer m.socketsLock.Unlock()
+
+	for _, s := range m.sockets {
+		if s.active {
+			s.sendConnect()
+		}
+	}
+}
+
+/**
+Engine.io connection lost, disconnect every socket
+*/
+func (m *Manager) onClose(c *Channel) {
+	m.socketsLock.Lock()
+	sockets := make([]*Socket, 0, len(m.sockets))
+	for _, s := range m.sockets {
+		sockets = append(sockets, s)
+	}
+	m.socketsLock.Unlock()
+
+	reason := c.DisconnectReason()
+	for _, s := range sockets {
+		s.disconnected(reason)
+	}
+}
+
+/**
+Route packet to socket of its namespace
+*/
+func (m *Manager) onPacket(c *Channel, msg *protocol.Message) {
+	s := m.socket(msg.Namespace)
+	if s == nil {
+		return
+	}
+
+	switch msg.Type {
+	case protocol.MessageTypeEmpty:
+		s.connected()
+	case protocol.MessageTypeConnectError:
+		s.aliveLock.Lock()
+		handshake := s.handshake
+		s.aliveLock.Unlock()
+		handshakeDone(handshake, newConnectError(msg.Args))
+	case protocol.MessageTypeDisconnect:
+		//kicked by server, do not join again after reconnection
+		m.socketsLock.Lock()
+		s.active = false
+		m.socketsLock.Unlock()
+		s.disconnected(ReasonServerDisconnect)
+	default:
+		s.processIncomingMessage(&s.Channel, msg)
+	}
+}
+
+/**
+Join namespace of the socket with optional auth payload, blocks until server
+accepts it, ctx is done or handshake timeout of manager expires
+Socket joins the namespace again after every reconnection
+*/
+func (s *Socket) Connect(ctx context.Context, auth interface{}) error {
+	handshake := make(chan error, 1)
+	s.aliveLock.Lock()
+	if s.alive {
+		s.aliveLock.Unlock()
+		return nil
+	}
+	if auth != nil {
+		data, err := json.Marshal(auth)
+		if err != nil {
+			s.aliveLock.Unlock()
+			return err
+		}
+		s.auth = string(data)
+	}
+	s.handshake = handshake
+	s.aliveLock.Unlock()
+	s.transition(&s.Channel, StateConnecting, StateClosed)
+
+	m := s.manager
+	m.socketsLock.Lock()
+	s.active = true
+	m.sockets[s.namespace] = s
+	s.sendConnect()
+	m.socketsLock.Unlock()
+
+	if s.namespace == "" && s.authPayload() == "" && m.engine.IsAlive() {
+		//server connects default namespace by itself with engine.io handshake
+		s.connected()
+	}
+	return waitHandshake(ctx, handshake, m.engine.HandshakeTimeout)
+}
+
+/**
+Leave namespace, socket is removed from manager and gets disconnected
+*/
+func (s *Socket) Close() {
+	m := s.manager
+	m.socketsLock.Lock()
+	s.active = false
+	if m.sockets[s.namespace] == s {
+		delete(m.sockets, s.namespace)
+	}
+	m.socketsLock.Unlock()
+
+	if s.transition(&s.Channel, StateClosing, StateOpen) {
+		s.engine.enqueueText(protocol.MustEncode(&protocol.Message{
+			Type:      protocol.MessageTypeDisconnect,
+			Namespace: s.namespace,
+		}))
+	}
+	s.disconnected(ReasonClientDisconnect)
+}
+
+/**
+Get namespace of the socket, empty for default namespace
+*/
+func (s *Socket) Namespace() string {
+	return s.namespace
+}
+
+/**
+Send namespace connect packet, default namespace is connected by server
+itself unless auth is given
+*/
+func (s *Socket) sendConnect() {
+	auth := s.authPayload()
+	if s.namespace == "" && auth == "" {
+		return
+	}
+	s.engine.enqueueText(protocol.MustEncode(&protocol.Message{
+		Type:      protocol.MessageTypeEmpty,
+		Namespace: s.namespace,
+		Args:      auth,
+	}))
+}
+
+/**
+Namespace connected, send what was emitted while offline
+*/
+func (s *Socket) connected() {
+	s.aliveLock.Lock()
+	if s.alive || (s.state != StateConnecting && s.state != StateReconnecting) {
+		s.aliveLock.Unlock()
+		return
+	}
+	s.alive = true
+	s.reason = ""
+	handshake := s.handshake
+	s.aliveLock.Unlock()
+
+	if s.offline != nil {
+		_, out := s.current()
+		s.offline.flush(out)
+	}
+	s.transition(&s.Channel, StateOpen, StateConnecting, StateReconnecting)
+	handshakeDone(handshake, nil)
+}
+
+/**
+Namespace or connection lost, fail or keep pending acks as channel does
+Active socket waits for reconnection of manager, others get closed
+*/
+func (s *Socket) disconnected(reason string) {
+	m := s.manager
+	m.socketsLock.Lock()
+	active := s.active
+	m.socketsLock.Unlock()
+
+	to := StateClosed
+	if active && m.engine.State() == StateReconnecting {
+		to = StateReconnecting
+	}
+
+	s.aliveLock.Lock()
+	alive := s.alive
+	if alive {
+		s.reason = reason
+	}
+	s.alive = false
+	handshake := s.handshake
+	from := s.state
+	s.aliveLock.Unlock()
+
+	handshakeDone(handshake, ErrorHandshakeClosed)
+
+	if alive {
+		if s.offline != nil {
+			s.offline.goOffline()
+		}
+		resend := s.ack.disconnected(s.offline)
+		if s.offline != nil {
+			s.offline.requeue(resend)
+		}
+	}
+	s.transition(&s.Channel, to, from)
+}
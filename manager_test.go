@@ -0,0 +1,84 @@
+package gosocketio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/**
+Test server accepting every namespace, it sends news event to each namespace
+connected and reports packets it got
+*/
+func namespaceServer(t *testing.T, received chan string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		socket, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer socket.Close()
+
+		socket.WriteMessage(websocket.TextMessage,
+			[]byte(`0{"sid":"abc","pingInterval":25000,"pingTimeout":60000}`))
+		socket.WriteMessage(websocket.TextMessage, []byte("40"))
+		for {
+			_, data, err := socket.ReadMessage()
+			if err != nil {
+				return
+			}
+			text := string(data)
+			received <- text
+			if len(text) > 3 && text[:3] == "40/" {
+				nsp := strings.TrimSuffix(text[2:], ",")
+				socket.WriteMessage(websocket.TextMessage, []byte("40"+nsp+","))
+				socket.WriteMessage(websocket.TextMessage, []byte(`42`+nsp+`,["news","`+nsp+`"]`))
+			}
+		}
+	}))
+}
+
+func TestManagerNamespaces(t *testing.T) {
+	received := make(chan string, 10)
+	ts := namespaceServer(t, received)
+	defer ts.Close()
+
+	m, err := DialManager(context.Background(), ts.URL)
+	require.NoError(t, err)
+	defer m.Close()
+
+	news := make(chan string, 10)
+	chat := m.Socket("/chat")
+	chat.On("news", func(h *Channel, data []byte) {
+		news <- "chat:" + string(data)
+	})
+	root := m.Socket("/")
+	root.On("news", func(h *Channel, data []byte) {
+		news <- "root:" + string(data)
+	})
+
+	require.NoError(t, root.Connect(context.Background(), nil))
+	require.NoError(t, chat.Connect(context.Background(), nil))
+	assert.Equal(t, "40/chat,", <-received)
+	assert.True(t, chat.IsAlive())
+	assert.Equal(t, m.Id(), chat.Id())
+
+	select {
+	case got := <-news:
+		assert.Equal(t, "chat:/chat", got)
+	case <-time.After(time.Second):
+		t.Fatal("no event of chat namespace")
+	}
+
+	chat.Close()
+	assert.False(t, chat.IsAlive())
+	assert.Equal(t, "41/chat,", <-received)
+	assert.True(t, root.IsAlive())
+}
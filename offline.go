@@ -0,0 +1,150 @@
+package gosocketio
+
+import (
+	"sync"
+)
+
+const (
+	//buffer size of offline packets if it is not set
+	offlineBufferSize = 100
+)
+
+/**
+Client offline buffer settings
+
+Packets emitted while client is disconnected or reconnecting are kept, up to
+BufferSize, and sent in order once namespace is connected again. Zero
+BufferSize means 100 packets
+OverflowDropOldest discards the oldest buffered packet when buffer is full,
+any other policy discards the new one
+*/
+type OfflineConfig struct {
+	BufferSize     int
+	OverflowPolicy OverflowPolicy
+}
+
+/**
+Keeps packets sent while client is offline
+*/
+type offlineBuffer struct {
+	config  OfflineConfig
+	packets []*packet
+	online  bool
+	dropped uint64
+	lock    sync.Mutex
+}
+
+func newOfflineBuffer(config OfflineConfig) *offlineBuffer {
+	if config.BufferSize <= 0 {
+		config.BufferSize = offlineBufferSize
+	}
+	return &offlineBuffer{config: config}
+}
+
+/**
+Buffer packet if client is offline, returns false if it is online and packet
+should go to outgoing queue
+*/
+func (b *offlineBuffer) push(p *packet) (bool, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.online {
+		return false, nil
+	}
+	if p.volatile {
+		b.dropped++
+		return true, ErrorPacketDropped
+	}
+
+	if len(b.packets) >= b.config.BufferSize {
+		b.dropped++
+		if b.config.OverflowPolicy != OverflowDropOldest || len(b.packets) == 0 {
+			return true, ErrorPacketDropped
+		}
+		b.packets = b.packets[1:]
+	}
+	b.packets = append(b.packets, p)
+	return true, nil
+}
+
+/**
+Put packets of unanswered acks before the buffered ones, skipping packets
+which are buffered already
+*/
+func (b *offlineBuffer) requeue(packets []*packet) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var resend []*packet
+	for _, p := range packets {
+		if !b.hasPacket(p) {
+			resend = append(resend, p)
+		}
+	}
+	b.packets = append(resend, b.packets...)
+}
+
+/**
+Check if packet waits in buffer
+*/
+func (b *offlineBuffer) contains(p *packet) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.hasPacket(p)
+}
+
+/**
+Should be called with lock held
+*/
+func (b *offlineBuffer) hasPacket(p *packet) bool {
+	for _, cur := range b.packets {
+		if cur == p {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+Client lost connection, buffer packets from now on
+*/
+func (b *offlineBuffer) goOffline() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.online = false
+}
+
+/**
+Namespace is connected, move buffered packets to outgoing queue in order
+Buffering goes on if queue refuses packets, so the rest is kept
+*/
+func (b *offlineBuffer) flush(out *outQueue) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for len(b.packets) > 0 {
+		err := out.push(b.packets[0])
+		if err == ErrorChannelClosed {
+			return
+		}
+		if err != nil {
+			b.dropped++
+		}
+		b.packets[0] = nil
+		b.packets = b.packets[1:]
+	}
+	b.online = true
+}
+
+/**
+Amount of packets dropped by buffer overflow
+*/
+func (b *offlineBuffer) droppedCount() uint64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.dropped
+}
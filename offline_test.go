@@ -0,0 +1,85 @@
+package gosocketio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func popTexts(q *outQueue) []string {
+	var texts []string
+	for q.len() > 0 {
+		p, _ := q.pop()
+		texts = append(texts, p.text)
+	}
+	return texts
+}
+
+func TestOfflineBufferFlush(t *testing.T) {
+	b := newOfflineBuffer(OfflineConfig{BufferSize: 2})
+
+	buffered, err := b.push(&packet{text: "a"})
+	assert.True(t, buffered)
+	assert.NoError(t, err)
+	b.push(&packet{text: "b"})
+
+	buffered, err = b.push(&packet{text: "c"})
+	assert.True(t, buffered)
+	assert.Equal(t, ErrorPacketDropped, err)
+
+	q := newTestQueue(OverflowDisconnect, 10)
+	b.flush(q)
+	assert.Equal(t, []string{"a", "b"}, popTexts(q))
+
+	buffered, _ = b.push(&packet{text: "d"})
+	assert.False(t, buffered)
+}
+
+func TestOfflineBufferDefaultSize(t *testing.T) {
+	b := newOfflineBuffer(OfflineConfig{})
+
+	for i := 0; i < offlineBufferSize; i++ {
+		buffered, err := b.push(&packet{text: "a"})
+		assert.True(t, buffered)
+		assert.NoError(t, err)
+	}
+	_, err := b.push(&packet{text: "b"})
+	assert.Equal(t, ErrorPacketDropped, err)
+}
+
+func TestOfflineBufferDropOldest(t *testing.T) {
+	b := newOfflineBuffer(OfflineConfig{BufferSize: 2, OverflowPolicy: OverflowDropOldest})
+
+	b.push(&packet{text: "a"})
+	b.push(&packet{text: "b"})
+	_, err := b.push(&packet{text: "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), b.droppedCount())
+
+	q := newTestQueue(OverflowDisconnect, 10)
+	b.flush(q)
+	assert.Equal(t, []string{"b", "c"}, popTexts(q))
+}
+
+func TestAckDisconnected(t *testing.T) {
+	a := ackProcessor{resultWaiters: make(map[int]*ackWaiter)}
+	b := newOfflineBuffer(OfflineConfig{BufferSize: 10})
+
+	failed := newAckWaiter(&packet{text: "421[\"a\"]"}, false)
+	resent2 := newAckWaiter(&packet{text: "423[\"c\"]"}, true)
+	resent1 := newAckWaiter(&packet{text: "422[\"b\"]"}, true)
+	buffered := newAckWaiter(&packet{text: "424[\"d\"]"}, false)
+	a.addWaiter(1, failed)
+	a.addWaiter(3, resent2)
+	a.addWaiter(2, resent1)
+	a.addWaiter(4, buffered)
+	b.push(buffered.packet)
+
+	b.requeue(a.disconnected(b))
+	assert.Equal(t, ErrorAckDisconnected, <-failed.err)
+	assert.Equal(t, 3, a.amount())
+
+	q := newTestQueue(OverflowDisconnect, 10)
+	b.flush(q)
+	assert.Equal(t, []string{"422[\"b\"]", "423[\"c\"]", "424[\"d\"]"}, popTexts(q))
+}
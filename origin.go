@@ -0,0 +1,119 @@
+package gosocketio
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+Allowed origins of browser clients and CORS settings of server
+
+Origin is allowed if it is listed in AllowedOrigins, or if AllowOriginFunc
+returns true for it. Entries may be exact origins like "https://example.com",
+wildcard subdomains like "https://*.example.com", or "*" for any origin.
+Origins allowed only by "*" never get credentials, even with AllowCredentials.
+Requests without Origin header come from non-browser clients and are allowed.
+Disallowed requests get 403 before the upgrade
+*/
+type OriginPolicy struct {
+	AllowedOrigins  []string
+	AllowOriginFunc func(origin string, r *http.Request) bool
+
+	//CORS settings of polling requests, websocket upgrade does not use them
+	AllowCredentials bool
+	//headers allowed by preflight response, empty allows requested ones
+	AllowedHeaders []string
+	//how long browser may cache preflight response, zero means not set
+	MaxAge time.Duration
+}
+
+/**
+Check if origin is allowed by the policy
+*/
+func (p *OriginPolicy) Allowed(origin string, r *http.Request) bool {
+	allowed, _ := p.allow(origin, r)
+	return allowed
+}
+
+/**
+Check if origin is allowed, and if it is allowed by anything but "*", which
+is required to send credentials to it
+*/
+func (p *OriginPolicy) allow(origin string, r *http.Request) (allowed, listed bool) {
+	lower := strings.ToLower(origin)
+	for _, pattern := range p.AllowedOrigins {
+		if pattern != "*" && matchOrigin(strings.ToLower(pattern), lower) {
+			return true, true
+		}
+	}
+	if p.AllowOriginFunc != nil && p.AllowOriginFunc(origin, r) {
+		return true, true
+	}
+	for _, pattern := range p.AllowedOrigins {
+		if pattern == "*" {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+/**
+Match origin against exact or wildcard pattern
+*/
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+
+	i := strings.Index(pattern, "*.")
+	if i == -1 {
+		return false
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+/**
+Check origin of request and write CORS headers, preflight and rejected
+requests are answered, returns false if request should not be served further
+*/
+func (p *OriginPolicy) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	allowed, listed := p.allow(origin, r)
+	if !allowed {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return false
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return true
+	}
+
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Add("Vary", "Origin")
+	if p.AllowCredentials && listed {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if r.Method != http.MethodOptions {
+		return true
+	}
+
+	//preflight
+	header.Set("Access-Control-Allow-Methods", "GET, POST")
+	if len(p.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if p.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return false
+}
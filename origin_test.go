@@ -0,0 +1,95 @@
+package gosocketio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOriginPolicyAllowed(t *testing.T) {
+	p := &OriginPolicy{
+		AllowedOrigins: []string{"https://example.com", "https://*.example.org"},
+		AllowOriginFunc: func(origin string, r *http.Request) bool {
+			return origin == "http://localhost:8080"
+		},
+	}
+
+	assert.True(t, p.Allowed("https://example.com", nil))
+	assert.True(t, p.Allowed("https://EXAMPLE.com", nil))
+	assert.True(t, p.Allowed("https://app.example.org", nil))
+	assert.True(t, p.Allowed("https://a.b.example.org", nil))
+	assert.True(t, p.Allowed("http://localhost:8080", nil))
+	assert.False(t, p.Allowed("https://example.org", nil))
+	assert.False(t, p.Allowed("https://evil-example.org", nil))
+	assert.False(t, p.Allowed("http://app.example.org", nil))
+	assert.False(t, p.Allowed("https://example.com.evil.net", nil))
+}
+
+func TestOriginPolicyPreflight(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.Origins = &OriginPolicy{
+		AllowedOrigins:   []string{"https://example.com"},
+		AllowCredentials: true,
+		MaxAge:           time.Minute,
+	}
+
+	r := httptest.NewRequest(http.MethodOptions, "/socket.io/?EIO=3&transport=polling", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Headers", "Authorization")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "60", w.Header().Get("Access-Control-Max-Age"))
+
+	r.Header.Set("Origin", "https://evil.com")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestOriginPolicyWildcardCredentials(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.Origins = &OriginPolicy{
+		AllowedOrigins:   []string{"*", "https://example.com"},
+		AllowCredentials: true,
+	}
+
+	r := httptest.NewRequest(http.MethodOptions, "/socket.io/?EIO=3&transport=polling", nil)
+	r.Header.Set("Origin", "https://evil.com")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://evil.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	r.Header.Set("Origin", "https://example.com")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestOriginPolicyUpgrade(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.Origins = &OriginPolicy{AllowedOrigins: []string{"https://example.com"}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	c, err := Dial(context.Background(), ts.URL,
+		WithHeader(http.Header{"Origin": {"https://example.com"}}))
+	require.NoError(t, err)
+	c.Close()
+
+	_, err = Dial(context.Background(), ts.URL,
+		WithHeader(http.Header{"Origin": {"https://evil.com"}}))
+	assert.Error(t, err)
+}
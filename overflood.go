@@ -0,0 +1,74 @@
+package gosocketio
+
+import (
+	"sync"
+	"time"
+)
+
+/**
+Channel with outgoing queue over its high watermark
+*/
+type OverfloodedChannel struct {
+	Channel    *Channel
+	QueueDepth int
+	Since      time.Time
+	Duration   time.Duration
+}
+
+/**
+Tracks channels over high watermark, one registry per server or client
+*/
+type overfloodRegistry struct {
+	channels map[*Channel]time.Time
+	lock     sync.Mutex
+}
+
+/**
+Mark channel as overflooded, keeps the time it was marked first
+*/
+func (r *overfloodRegistry) add(c *Channel) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.channels == nil {
+		r.channels = make(map[*Channel]time.Time)
+	}
+	if _, ok := r.channels[c]; !ok {
+		r.channels[c] = time.Now()
+	}
+}
+
+func (r *overfloodRegistry) remove(c *Channel) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.channels, c)
+}
+
+func (r *overfloodRegistry) amount() int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return int64(len(r.channels))
+}
+
+/**
+Get overflooded channels with their current queue depth
+*/
+func (r *overfloodRegistry) list() []OverfloodedChannel {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	result := make([]OverfloodedChannel, 0, len(r.channels))
+	for c, since := range r.channels {
+		_, out := c.current()
+		result = append(result, OverfloodedChannel{
+			Channel:    c,
+			QueueDepth: out.len(),
+			Since:      since,
+			Duration:   now.Sub(since),
+		})
+	}
+	return result
+}
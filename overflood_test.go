@@ -0,0 +1,41 @@
+package gosocketio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOverflooded(t *testing.T) {
+	registry := &overfloodRegistry{}
+	c := &Channel{}
+	c.conn = &nopConnection{}
+	c.initChannel(&methods{}, QueueConfig{QueueBufferSize: 8, HighWatermark: 4, LowWatermark: 1}, registry)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.enqueue(&packet{text: "a"}))
+	}
+	assert.Empty(t, registry.list())
+
+	require.NoError(t, c.enqueue(&packet{text: "a"}))
+	require.NoError(t, c.enqueue(&packet{text: "a"}))
+	time.Sleep(10 * time.Millisecond)
+
+	list := registry.list()
+	require.Len(t, list, 1)
+	assert.Equal(t, c, list[0].Channel)
+	assert.Equal(t, 5, list[0].QueueDepth)
+	assert.True(t, list[0].Duration >= 10*time.Millisecond)
+	assert.Equal(t, int64(1), registry.amount())
+
+	_, out := c.current()
+	for i := 0; i < 3; i++ {
+		out.pop()
+	}
+	assert.Len(t, registry.list(), 1)
+	out.pop()
+	assert.Empty(t, registry.list())
+	assert.Equal(t, int64(0), registry.amount())
+}
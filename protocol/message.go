@@ -38,6 +38,14 @@ const (
 	ack response
 	*/
 	MessageTypeAckResponse = iota
+	/**
+	Namespace connect refused, args is error payload
+	*/
+	MessageTypeConnectError = iota
+	/**
+	Namespace disconnect, args is optional reason
+	*/
+	MessageTypeDisconnect = iota
 )
 
 //msg总数据，可能包含多个二进制数据帧
@@ -49,5 +57,7 @@ type Message struct {
 	Source []byte
 	Data   []byte
 	Num      int
+	//socket.io namespace, empty for default "/" namespace
+	Namespace string
 }
 
@@ -40,6 +40,8 @@ const (
 	msg          = "4"
 
 	ConnectMessage     = "40"
+	disconnectMessage  = "41"
+	errorMessage       = "44"
 	EventMessage       = "42" //4 means websocket msg, 2 means socket.io msg, not ack
 	ackMessage         = "43"
 	binaryEventMessage = "45"
@@ -69,6 +71,10 @@ func typeToText(msgType int) (string, error) {
 		return EventMessage, nil
 	case MessageTypeAckResponse:
 		return ackMessage, nil
+	case MessageTypeConnectError:
+		return errorMessage, nil
+	case MessageTypeDisconnect:
+		return disconnectMessage, nil
 	}
 	return "", ErrorWrongMessageType
 }
@@ -79,19 +85,30 @@ func Encode(msg *Message) (string, error) {
 		return "", err
 	}
 
-	if msg.Type == MessageTypeEmpty || msg.Type == MessageTypePing ||
-		msg.Type == MessageTypePong {
+	if msg.Type == MessageTypePing || msg.Type == MessageTypePong {
 		return result, nil
 	}
 
-	if msg.Type == MessageTypeAckRequest || msg.Type == MessageTypeAckResponse {
-		result += strconv.Itoa(msg.AckId)
+	if msg.Type == MessageTypeEmpty || msg.Type == MessageTypeConnectError ||
+		msg.Type == MessageTypeDisconnect {
+		//connect packet, args is optional auth payload or error
+		return result + namespacePrefix(msg.Namespace) + msg.Args, nil
 	}
 
 	if msg.Type == MessageTypeEmit {
 		result += strconv.Itoa(msg.Num)
 		result += "-"
 	}
+
+	if msg.Type == MessageTypeEmit || msg.Type == MessageTypeAckRequest ||
+		msg.Type == MessageTypeAckResponse {
+		result += namespacePrefix(msg.Namespace)
+	}
+
+	if msg.Type == MessageTypeAckRequest || msg.Type == MessageTypeAckResponse {
+		result += strconv.Itoa(msg.AckId)
+	}
+
 	if msg.Type == MessageTypeOpen || msg.Type == MessageTypeClose {
 		return result + msg.Args, nil
 	}
@@ -105,9 +122,48 @@ func Encode(msg *Message) (string, error) {
 		return "", err
 	}
 
+	if msg.Args == "" {
+		//event without arguments
+		return result + "[" + string(jsonMethod) + "]", nil
+	}
 	return result + "[" + string(jsonMethod) + "," + msg.Args + "]", nil
 }
 
+/**
+Namespace part of socket.io packet, empty for default namespace
+*/
+func namespacePrefix(namespace string) string {
+	if namespace == "" || namespace == "/" {
+		return ""
+	}
+	return namespace + ","
+}
+
+/**
+Cut namespace out of socket.io packet, returns the packet without it
+Namespace goes after packet type and binary attachments amount
+*/
+func getNamespace(text string) (namespace, restText string) {
+	if len(text) < 2 || text[0:1] != msg {
+		return "", text
+	}
+
+	start := 2
+	if i := strings.IndexByte(text, '-'); i != -1 &&
+		(text[0:2] == binaryEventMessage || text[0:2] == binaryAckMessage) {
+		start = i + 1
+	}
+	if len(text) <= start || text[start] != '/' {
+		return "", text
+	}
+
+	end := strings.IndexByte(text[start:], ',')
+	if end == -1 {
+		return text[start:], text[:start]
+	}
+	return text[start : start+end], text[:start] + text[start+end+1:]
+}
+
 func MustEncode(msg *Message) string {
 	result, err := Encode(msg)
 	if err != nil {
@@ -148,6 +204,10 @@ func getMessageType(databuf []byte) (int, int, error) {
 			return MessageTypeAckRequest, v, nil
 		case ackMessage:
 			return MessageTypeAckResponse, 0, nil
+		case errorMessage:
+			return MessageTypeConnectError, 0, nil
+		case disconnectMessage:
+			return MessageTypeDisconnect, 0, nil
 		case binaryAckMessage:
 			i := strings.Index(string(data), "-")
 			x := string(data[2:i])
@@ -217,6 +277,13 @@ func getMethod(text string) (method, restText string, err error) {
 	//
 	arr := []string{}
 	json.Unmarshal([]byte(text),&arr)
+	switch len(arr) {
+	case 0:
+		return "", "", ErrorWrongPacket
+	case 1:
+		//event without arguments
+		return arr[0], "", nil
+	}
 	return arr[0],arr[1],nil
 }
 
@@ -236,8 +303,19 @@ func Decode(data []byte) (*Message, error) {
 		return msg, nil
 	}
 
+	var text string
+	msg.Namespace, text = getNamespace(string(data))
+	data = []byte(text)
+
+	if msg.Type == MessageTypeEmpty || msg.Type == MessageTypeConnectError ||
+		msg.Type == MessageTypeDisconnect {
+		//connect packet may carry auth payload, connect error its reason
+		msg.Args = text[2:]
+		return msg, nil
+	}
+
 	if msg.Type == MessageTypeClose || msg.Type == MessageTypePing ||
-		msg.Type == MessageTypePong || msg.Type == MessageTypeEmpty {
+		msg.Type == MessageTypePong {
 		//fmt.Println("msg.Source  >>> ",msg.Type, string(msg.Source))
 		return msg, nil
 	}
@@ -0,0 +1,290 @@
+package gosocketio
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+/**
+What to do with a packet when outgoing queue of a slow consumer is full
+*/
+type OverflowPolicy int
+
+const (
+	/**
+	Close the connection, default policy, disconnection reason is
+	ReasonQueueOverflow
+	*/
+	OverflowDisconnect OverflowPolicy = iota
+	/**
+	Discard the packet being sent
+	*/
+	OverflowDropNewest
+	/**
+	Discard the oldest queued packet which is not a control packet
+	*/
+	OverflowDropOldest
+	/**
+	Block the sender until there is free space or OverflowTimeout expires,
+	5 seconds if it is not set
+	*/
+	OverflowBlock
+)
+
+const (
+	//how long OverflowBlock waits for free space if timeout is not set
+	overflowTimeout = 5 * time.Second
+)
+
+var (
+	ErrorPacketDropped = errors.New("Packet dropped")
+	ErrorChannelClosed = errors.New("Channel closed")
+)
+
+/**
+Outgoing queue settings, shared by server and client
+
+Watermarks are amounts of queued packets, OnWatermark is called with high
+set when queue grows up to HighWatermark and with high unset when it
+shrinks back to LowWatermark
+*/
+type QueueConfig struct {
+	QueueBufferSize int
+	OverflowPolicy  OverflowPolicy
+	OverflowTimeout time.Duration
+
+	HighWatermark int
+	LowWatermark  int
+	OnWatermark   func(c *Channel, high bool)
+}
+
+/**
+Get config with zero values replaced by defaults
+*/
+func (qc QueueConfig) withDefaults() QueueConfig {
+	if qc.QueueBufferSize <= 0 {
+		qc.QueueBufferSize = queueBufferSize
+	}
+	if qc.OverflowTimeout <= 0 {
+		qc.OverflowTimeout = overflowTimeout
+	}
+	if qc.HighWatermark <= 0 || qc.HighWatermark > qc.QueueBufferSize {
+		qc.HighWatermark = qc.QueueBufferSize / 2
+	}
+	if qc.LowWatermark <= 0 || qc.LowWatermark > qc.HighWatermark {
+		qc.LowWatermark = qc.HighWatermark / 2
+	}
+	return qc
+}
+
+/**
+Bounded queue of packets waiting to be written by outLoop
+*/
+type outQueue struct {
+	config QueueConfig
+
+	packets  []*packet
+	closed   bool
+	overHigh bool
+	dropped  uint64
+	maxLen   int
+	lock     sync.Mutex
+
+	//signals outLoop that packet was pushed or queue closed
+	ready chan struct{}
+	//closed and replaced each time packet is taken, wakes blocked senders
+	freed chan struct{}
+
+	onWatermark func(high bool)
+}
+
+func newOutQueue(config QueueConfig, onWatermark func(high bool)) *outQueue {
+	return &outQueue{
+		config:      config,
+		ready:       make(chan struct{}, 1),
+		freed:       make(chan struct{}),
+		onWatermark: onWatermark,
+	}
+}
+
+/**
+Amount of queued packets
+*/
+func (q *outQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.packets)
+}
+
+/**
+Biggest amount of packets queued at once
+*/
+func (q *outQueue) highWaterMark() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.maxLen
+}
+
+/**
+Amount of packets dropped by overflow policy or as volatile
+*/
+func (q *outQueue) droppedCount() uint64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.dropped
+}
+
+/**
+Add packet to the end of queue, applying overflow policy if queue is full
+Control packets are never refused, volatile packets are refused while queue
+is over high watermark
+*/
+func (q *outQueue) push(p *packet) error {
+	var deadline <-chan time.Time
+
+	for {
+		q.lock.Lock()
+		if q.closed {
+			q.lock.Unlock()
+			return ErrorChannelClosed
+		}
+
+		if p.volatile && q.overHigh {
+			q.dropped++
+			q.lock.Unlock()
+			return ErrorPacketDropped
+		}
+
+		if len(q.packets) < q.config.QueueBufferSize || p.control {
+			q.append(p)
+			return nil
+		}
+
+		switch q.config.OverflowPolicy {
+		case OverflowDropNewest:
+			q.dropped++
+			q.lock.Unlock()
+			return ErrorPacketDropped
+		case OverflowDropOldest:
+			q.dropped++
+			if q.dropOldest() {
+				q.append(p)
+				return nil
+			}
+			q.lock.Unlock()
+			return ErrorPacketDropped
+		case OverflowBlock:
+			freed := q.freed
+			q.lock.Unlock()
+
+			if deadline == nil {
+				deadline = time.After(q.config.OverflowTimeout)
+			}
+			select {
+			case <-freed:
+			case <-deadline:
+				return ErrorSendTimeout
+			}
+		default:
+			q.lock.Unlock()
+			return ErrorSocketOverflood
+		}
+	}
+}
+
+/**
+Append packet and release the lock, should be called with lock held
+*/
+func (q *outQueue) append(p *packet) {
+	q.packets = append(q.packets, p)
+	if len(q.packets) > q.maxLen {
+		q.maxLen = len(q.packets)
+	}
+	crossed := !q.overHigh && len(q.packets) >= q.config.HighWatermark
+	if crossed {
+		q.overHigh = true
+	}
+	q.lock.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	if crossed && q.onWatermark != nil {
+		q.onWatermark(true)
+	}
+}
+
+/**
+Remove oldest non-control packet, should be called with lock held
+*/
+func (q *outQueue) dropOldest() bool {
+	for i, p := range q.packets {
+		if !p.control {
+			q.packets = append(q.packets[:i], q.packets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+/**
+Take first packet from queue, block until there is one
+Returns false if queue is closed
+*/
+func (q *outQueue) pop() (*packet, bool) {
+	for {
+		q.lock.Lock()
+		if q.closed {
+			q.lock.Unlock()
+			return nil, false
+		}
+
+		if len(q.packets) > 0 {
+			p := q.packets[0]
+			q.packets[0] = nil
+			q.packets = q.packets[1:]
+
+			close(q.freed)
+			q.freed = make(chan struct{})
+
+			crossed := q.overHigh && len(q.packets) <= q.config.LowWatermark
+			if crossed {
+				q.overHigh = false
+			}
+			q.lock.Unlock()
+
+			if crossed && q.onWatermark != nil {
+				q.onWatermark(false)
+			}
+			return p, true
+		}
+		q.lock.Unlock()
+
+		<-q.ready
+	}
+}
+
+/**
+Drop all queued packets and stop the queue, pop and push fail after that
+*/
+func (q *outQueue) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.packets = nil
+	close(q.freed)
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,139 @@
+package gosocketio
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestQueue(policy OverflowPolicy, size int) *outQueue {
+	config := QueueConfig{QueueBufferSize: size, OverflowPolicy: policy}
+	return newOutQueue(config.withDefaults(), nil)
+}
+
+func TestQueueDisconnect(t *testing.T) {
+	q := newTestQueue(OverflowDisconnect, 2)
+
+	assert.NoError(t, q.push(&packet{text: "a"}))
+	assert.NoError(t, q.push(&packet{text: "b"}))
+	assert.Equal(t, ErrorSocketOverflood, q.push(&packet{text: "c"}))
+	assert.NoError(t, q.push(&packet{text: "3", control: true}))
+	assert.Equal(t, 3, q.len())
+}
+
+func TestQueueDropNewest(t *testing.T) {
+	q := newTestQueue(OverflowDropNewest, 1)
+
+	assert.NoError(t, q.push(&packet{text: "a"}))
+	assert.Equal(t, ErrorPacketDropped, q.push(&packet{text: "b"}))
+
+	p, ok := q.pop()
+	assert.True(t, ok)
+	assert.Equal(t, "a", p.text)
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	q := newTestQueue(OverflowDropOldest, 2)
+
+	assert.NoError(t, q.push(&packet{text: "2", control: true}))
+	assert.NoError(t, q.push(&packet{text: "a"}))
+	assert.NoError(t, q.push(&packet{text: "b"}))
+
+	var texts []string
+	for q.len() > 0 {
+		p, _ := q.pop()
+		texts = append(texts, p.text)
+	}
+	assert.Equal(t, []string{"2", "b"}, texts)
+}
+
+func TestQueueBlock(t *testing.T) {
+	q := newTestQueue(OverflowBlock, 1)
+	q.config.OverflowTimeout = 50 * time.Millisecond
+
+	assert.NoError(t, q.push(&packet{text: "a"}))
+	assert.Equal(t, ErrorSendTimeout, q.push(&packet{text: "b"}))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.pop()
+	}()
+	assert.NoError(t, q.push(&packet{text: "c"}))
+}
+
+func TestQueueWatermarks(t *testing.T) {
+	var marks []bool
+	config := QueueConfig{QueueBufferSize: 8, HighWatermark: 4, LowWatermark: 1}
+	q := newOutQueue(config.withDefaults(), func(high bool) {
+		marks = append(marks, high)
+	})
+
+	for i := 0; i < 5; i++ {
+		q.push(&packet{text: "a"})
+	}
+	assert.Equal(t, []bool{true}, marks)
+
+	for i := 0; i < 4; i++ {
+		q.pop()
+	}
+	assert.Equal(t, []bool{true, false}, marks)
+}
+
+func TestQueueClose(t *testing.T) {
+	q := newTestQueue(OverflowDisconnect, 2)
+	q.push(&packet{text: "a"})
+	q.close()
+
+	_, ok := q.pop()
+	assert.False(t, ok)
+	assert.Equal(t, ErrorChannelClosed, q.push(&packet{text: "b"}))
+}
+
+func TestQueueVolatile(t *testing.T) {
+	config := QueueConfig{QueueBufferSize: 4, HighWatermark: 2}
+	q := newOutQueue(config.withDefaults(), nil)
+
+	assert.NoError(t, q.push(&packet{text: "a", volatile: true}))
+	assert.NoError(t, q.push(&packet{text: "b"}))
+	assert.Equal(t, ErrorPacketDropped, q.push(&packet{text: "c", volatile: true}))
+	assert.NoError(t, q.push(&packet{text: "d"}))
+	assert.Equal(t, uint64(1), q.droppedCount())
+}
+
+func TestQueueBlockDefaultTimeout(t *testing.T) {
+	config := QueueConfig{OverflowPolicy: OverflowBlock}.withDefaults()
+	assert.Equal(t, overflowTimeout, config.OverflowTimeout)
+}
+
+type nopConnection struct {
+	transport.Connection
+	closed int32
+}
+
+func (c *nopConnection) Close() {
+	atomic.StoreInt32(&c.closed, 1)
+}
+
+func TestQueueOverflowReason(t *testing.T) {
+	reasons := make(chan string, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.On(OnDisconnection, func(h *Channel) {
+		reasons <- h.DisconnectReason()
+	})
+
+	conn := &nopConnection{}
+	c := &Channel{}
+	c.conn = conn
+	c.server = server
+	c.initChannel(&server.methods, QueueConfig{QueueBufferSize: 1}, &server.overflooded)
+	c.state = StateOpen
+
+	assert.NoError(t, c.enqueue(&packet{text: "a"}))
+	assert.Equal(t, ErrorSocketOverflood, c.enqueue(&packet{text: "b"}))
+	assert.Equal(t, ReasonQueueOverflow, <-reasons)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conn.closed))
+	assert.Equal(t, StateClosed, c.State())
+}
@@ -0,0 +1,148 @@
+package gosocketio
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	DefaultReconnectInitialDelay = 1 * time.Second
+	DefaultReconnectMaxDelay     = 5 * time.Second
+	DefaultReconnectFactor       = 2
+	DefaultReconnectRandomizer   = 0.5
+)
+
+/**
+Client reconnection settings
+
+MaxAttempts less or equal to zero means unlimited attempts
+Delay before attempt n is InitialDelay * Factor^(n-1), randomized by
++-RandomizationFactor of its value and limited by MaxDelay
+Zero InitialDelay, MaxDelay and Factor mean defaults, Factor is at least 1
+*/
+type ReconnectConfig struct {
+	MaxAttempts         int
+	InitialDelay        time.Duration
+	MaxDelay            time.Duration
+	Factor              float64
+	RandomizationFactor float64
+}
+
+/**
+Returns reconnection settings with default params and unlimited attempts
+*/
+func GetDefaultReconnectConfig() *ReconnectConfig {
+	return &ReconnectConfig{
+		InitialDelay:        DefaultReconnectInitialDelay,
+		MaxDelay:            DefaultReconnectMaxDelay,
+		Factor:              DefaultReconnectFactor,
+		RandomizationFactor: DefaultReconnectRandomizer,
+	}
+}
+
+/**
+Get config with zero values replaced by defaults
+*/
+func (rc ReconnectConfig) withDefaults() ReconnectConfig {
+	if rc.InitialDelay <= 0 {
+		rc.InitialDelay = DefaultReconnectInitialDelay
+	}
+	if rc.MaxDelay <= 0 {
+		rc.MaxDelay = DefaultReconnectMaxDelay
+	}
+	if rc.Factor <= 0 {
+		rc.Factor = DefaultReconnectFactor
+	} else if rc.Factor < 1 {
+		rc.Factor = 1
+	}
+	return rc
+}
+
+/**
+Get delay before given reconnection attempt, attempts are counted from 1
+*/
+func (rc *ReconnectConfig) delay(attempt int) time.Duration {
+	d := float64(rc.InitialDelay) * math.Pow(rc.Factor, float64(attempt-1))
+	if rc.RandomizationFactor > 0 {
+		deviation := rand.Float64() * rc.RandomizationFactor * d
+		if rand.Intn(2) == 0 {
+			d -= deviation
+		} else {
+			d += deviation
+		}
+	}
+	if rc.MaxDelay > 0 && d > float64(rc.MaxDelay) {
+		d = float64(rc.MaxDelay)
+	}
+	return time.Duration(d)
+}
+
+/**
+Reconnector of client, lost connection is reconnected unless client was
+closed or disconnected by server, is connecting already or reconnection is
+disabled
+*/
+func (c *Client) reconnectLost(ch *Channel) func() {
+	reason := ch.DisconnectReason()
+
+	c.reconnectLock.Lock()
+	defer c.reconnectLock.Unlock()
+
+	if c.Reconnect == nil || c.closed || c.reconnecting || c.dialing ||
+		reason == ReasonServerDisconnect {
+		return nil
+	}
+	c.reconnecting = true
+	return c.reconnect
+}
+
+/**
+Try to connect again until success, attempts are exhausted or client closed
+Fires reconnect_attempt, reconnect_error, reconnect and reconnect_failed events
+*/
+func (c *Client) reconnect() {
+	defer func() {
+		c.reconnectLock.Lock()
+		c.reconnecting = false
+		c.reconnectLock.Unlock()
+	}()
+
+	//dial of attempt in progress is cancelled when client is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	config := c.Reconnect.withDefaults()
+	for attempt := 1; config.MaxAttempts <= 0 || attempt <= config.MaxAttempts; attempt++ {
+		select {
+		case <-time.After(config.delay(attempt)):
+		case <-c.stop:
+			c.transition(&c.Channel, StateClosed, StateReconnecting)
+			return
+		}
+
+		c.callEvent(&c.Channel, OnReconnectAttempt, attempt)
+		if err := c.connect(ctx); err != nil {
+			if c.isClosed() {
+				c.transition(&c.Channel, StateClosed)
+				return
+			}
+			c.callEvent(&c.Channel, OnReconnectError, err)
+			continue
+		}
+
+		c.callEvent(&c.Channel, OnReconnect, attempt)
+		return
+	}
+
+	c.transition(&c.Channel, StateClosed, StateReconnecting)
+	c.callEvent(&c.Channel, OnReconnectFailed, nil)
+}
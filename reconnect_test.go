@@ -0,0 +1,165 @@
+package gosocketio
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconnectDelay(t *testing.T) {
+	config := &ReconnectConfig{
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Factor:       2,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, config.delay(1))
+	assert.Equal(t, 200*time.Millisecond, config.delay(2))
+	assert.Equal(t, 800*time.Millisecond, config.delay(4))
+	assert.Equal(t, time.Second, config.delay(5))
+}
+
+func TestReconnectConfigDefaults(t *testing.T) {
+	config := ReconnectConfig{}.withDefaults()
+	assert.Equal(t, DefaultReconnectInitialDelay, config.InitialDelay)
+	assert.Equal(t, DefaultReconnectMaxDelay, config.MaxDelay)
+	assert.Equal(t, float64(DefaultReconnectFactor), config.Factor)
+
+	config = ReconnectConfig{InitialDelay: time.Millisecond, Factor: 0.5}.withDefaults()
+	assert.Equal(t, float64(1), config.Factor)
+	assert.Equal(t, time.Millisecond, config.delay(10))
+}
+
+func TestReconnectZeroConfig(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.IpFilter = &IpFilter{}
+	server.On("drop", func(h *Channel) {
+		h.Close()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	attempts := int32(0)
+	c, err := Dial(context.Background(), ts.URL,
+		WithReconnect(&ReconnectConfig{}),
+		WithHandler(OnReconnectAttempt, func(h *Channel) {
+			atomic.AddInt32(&attempts, 1)
+		}),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	deny, err := ParsePrefixes([]string{"127.0.0.0/8", "::1"})
+	require.NoError(t, err)
+	server.IpFilter.Set(nil, deny)
+	require.NoError(t, c.Emit("drop", nil))
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, StateReconnecting, c.State())
+	assert.LessOrEqual(t, atomic.LoadInt32(&attempts), int32(2))
+}
+
+func TestReconnectDelayRandomized(t *testing.T) {
+	config := &ReconnectConfig{
+		InitialDelay:        100 * time.Millisecond,
+		MaxDelay:            time.Second,
+		Factor:              2,
+		RandomizationFactor: 0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		d := config.delay(2)
+		assert.True(t, d >= 100*time.Millisecond && d <= 300*time.Millisecond, d)
+	}
+}
+
+func TestCallEventArgs(t *testing.T) {
+	m := &methods{}
+	m.initMethods()
+
+	var attempt int
+	var reconnectErr error
+	var called bool
+	m.On(OnReconnectAttempt, func(c *Channel, n int) { attempt = n })
+	m.On(OnReconnectError, func(c *Channel, err error) { reconnectErr = err })
+	m.On(OnReconnectFailed, func(c *Channel) { called = true })
+
+	m.callEvent(nil, OnReconnectAttempt, 3)
+	m.callEvent(nil, OnReconnectError, errors.New("refused"))
+	m.callEvent(nil, OnReconnectFailed, nil)
+
+	assert.Equal(t, 3, attempt)
+	assert.EqualError(t, reconnectErr, "refused")
+	assert.True(t, called)
+}
+
+/**
+Transport which, once slowed, waits before connecting until delay passes or
+ctx is done, and connects anyway like a dial finishing after cancel
+*/
+type slowTransport struct {
+	*transport.WebsocketTransport
+	slow      int32
+	cancelled int32
+}
+
+func (st *slowTransport) ConnectContext(ctx context.Context,
+	url string) (transport.Connection, error) {
+
+	if atomic.LoadInt32(&st.slow) == 1 {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-ctx.Done():
+			atomic.StoreInt32(&st.cancelled, 1)
+		}
+	}
+	return st.WebsocketTransport.ConnectContext(context.Background(), url)
+}
+
+func TestCloseDuringReconnect(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.On("drop", func(h *Channel) {
+		h.Close()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	tr := &slowTransport{WebsocketTransport: transport.GetDefaultWebsocketTransport()}
+	attempts := make(chan struct{}, 1)
+	reconnected := int32(0)
+	c, err := Dial(context.Background(), ts.URL,
+		WithTransports(tr),
+		WithReconnect(&ReconnectConfig{InitialDelay: time.Millisecond}),
+		WithHandler(OnReconnectAttempt, func(h *Channel) {
+			attempts <- struct{}{}
+		}),
+		WithHandler(OnReconnect, func(h *Channel) {
+			atomic.AddInt32(&reconnected, 1)
+		}),
+	)
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&tr.slow, 1)
+	require.NoError(t, c.Emit("drop", nil))
+	select {
+	case <-attempts:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not try to reconnect")
+	}
+	time.Sleep(50 * time.Millisecond)
+	c.Close()
+
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tr.cancelled))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&reconnected))
+	assert.False(t, c.IsAlive())
+	assert.Equal(t, StateClosed, c.State())
+	assert.Equal(t, int64(0), server.AmountOfSids())
+}
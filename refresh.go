@@ -0,0 +1,151 @@
+package gosocketio
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/guhan121/golang-socketio/protocol"
+)
+
+const (
+	//reserved events of token refresh
+	//server asks client for fresh auth payload
+	OnTokenRequest = "token_request"
+	//client sends fresh auth payload to server
+	OnTokenRefresh = "token_refresh"
+	//server accepted fresh auth payload
+	OnTokenRefreshed = "token_refreshed"
+	//server refused fresh auth payload, with error message
+	OnTokenRefreshError = "token_refresh_error"
+)
+
+var (
+	ErrorRefreshNotSupported = errors.New("Token refresh is not supported")
+)
+
+/**
+Send fresh auth payload to server, it is validated by the hook validating
+auth payload of connect and replaces identity of the channel. Result comes
+as OnTokenRefreshed or OnTokenRefreshError event
+Client uses the payload for reconnections too
+*/
+func (c *Channel) RefreshToken(auth interface{}) error {
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return err
+	}
+
+	if c.server == nil {
+		c.aliveLock.Lock()
+		c.auth = string(data)
+		c.aliveLock.Unlock()
+	}
+	return c.Emit(OnTokenRefresh, string(data))
+}
+
+/**
+Get auth payload of namespace connect
+*/
+func (c *Channel) authPayload() string {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+
+	return c.auth
+}
+
+/**
+Filter incoming events of server: apply rate limits and handle reserved
+events of token refresh
+*/
+func (s *Server) filterEvent(c *Channel, msg *protocol.Message) bool {
+	if !s.limitEvent(c, msg) {
+		return false
+	}
+	if msg.Method == OnTokenRefresh {
+		s.refreshToken(c, msg.Args)
+		return false
+	}
+	return true
+}
+
+/**
+Validate fresh auth payload of client and replace identity of channel
+*/
+func (s *Server) refreshToken(c *Channel, auth string) {
+	authorize := s.Reauthorize
+	if authorize == nil {
+		authorize = s.AuthorizeConnect
+	}
+	if authorize == nil {
+		c.Emit(OnTokenRefreshError, ErrorRefreshNotSupported.Error())
+		return
+	}
+
+	identity, err := authorize(c, json.RawMessage(auth))
+	if err != nil {
+		message := err.Error()
+		if ce, ok := err.(*ConnectError); ok {
+			message = ce.Message
+		}
+		c.Emit(OnTokenRefreshError, message)
+		return
+	}
+
+	if identity != nil {
+		c.aliveLock.Lock()
+		c.identity = identity
+		c.aliveLock.Unlock()
+	}
+	s.watchExpiry(c)
+	c.Emit(OnTokenRefreshed, nil)
+}
+
+/**
+Ask client for fresh auth payload before identity expires and disconnect
+channel when it expires, as server is set to, replaces watch of previous
+identity. Payload is asked right away if identity expires sooner than
+RefreshBefore
+*/
+func (s *Server) watchExpiry(c *Channel) {
+	if !s.DisconnectOnExpiry && s.RefreshBefore <= 0 {
+		return
+	}
+
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+
+	c.stopExpiry()
+	identity, ok := c.identity.(Expiring)
+	if !ok || !c.alive {
+		return
+	}
+	at := identity.ExpiresAt()
+	if at.IsZero() {
+		return
+	}
+
+	wait := time.Until(at)
+	if s.RefreshBefore > 0 {
+		c.refresh = time.AfterFunc(wait-s.RefreshBefore, func() {
+			c.Emit(OnTokenRequest, nil)
+		})
+	}
+	c.expiry = time.AfterFunc(wait, func() {
+		c.Disconnect(ReasonTokenExpired, true)
+	})
+}
+
+/**
+Stop watch of identity expiry, aliveLock should be held
+*/
+func (c *Channel) stopExpiry() {
+	if c.refresh != nil {
+		c.refresh.Stop()
+		c.refresh = nil
+	}
+	if c.expiry != nil {
+		c.expiry.Stop()
+		c.expiry = nil
+	}
+}
@@ -0,0 +1,117 @@
+package gosocketio
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRefresh(t *testing.T) {
+	secret := []byte("secret")
+	a := &JWTAuth{Secret: secret}
+	disconnected := make(chan struct{}, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.Authorize = a.Authorize
+	server.Reauthorize = a.AuthorizeConnect
+	server.DisconnectOnExpiry = true
+	server.RefreshBefore = 1500 * time.Millisecond
+	server.On(OnDisconnection, func(h *Channel) {
+		disconnected <- struct{}{}
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	token := signToken(t, "HS256", secret, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(2 * time.Second).Unix(),
+	})
+	fresh := signToken(t, "HS256", secret, map[string]interface{}{
+		"sub": "bob",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	refreshed := make(chan struct{}, 1)
+	refused := make(chan struct{}, 1)
+	c, err := Dial(context.Background(), ts.URL,
+		WithQuery(url.Values{"token": {token}}),
+		WithTokenRefresh(func() (interface{}, error) {
+			return map[string]string{"token": fresh}, nil
+		}),
+		WithHandler(OnTokenRefreshed, func(h *Channel) {
+			refreshed <- struct{}{}
+		}),
+		WithHandler(OnTokenRefreshError, func(h *Channel) {
+			refused <- struct{}{}
+		}),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.RefreshToken(map[string]string{"token": "abc"}))
+	select {
+	case <-refused:
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalid token was not refused")
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("token was not refreshed")
+	}
+	ch, err := server.GetChannel(c.Id())
+	require.NoError(t, err)
+	assert.Equal(t, "bob", ch.Identity().(JWTClaims).Subject())
+
+	select {
+	case <-disconnected:
+		t.Fatal("refreshed socket was disconnected")
+	case <-time.After(2500 * time.Millisecond):
+	}
+	assert.True(t, c.IsAlive())
+}
+
+func TestTokenRefreshNotAnswered(t *testing.T) {
+	secret := []byte("secret")
+	a := &JWTAuth{Secret: secret}
+	disconnected := make(chan string, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.Authorize = a.Authorize
+	server.RefreshBefore = 5 * time.Second
+	server.On(OnDisconnection, func(h *Channel) {
+		disconnected <- h.DisconnectReason()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	token := signToken(t, "HS256", secret, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(2 * time.Second).Unix(),
+	})
+	requested := make(chan struct{}, 1)
+	c, err := Dial(context.Background(), ts.URL,
+		WithQuery(url.Values{"token": {token}}),
+		WithHandler(OnTokenRequest, func(h *Channel) {
+			requested <- struct{}{}
+		}),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	select {
+	case <-requested:
+	case <-time.After(time.Second):
+		t.Fatal("token was not requested right away")
+	}
+	select {
+	case reason := <-disconnected:
+		assert.Equal(t, ReasonTokenExpired, reason)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expired socket was not disconnected")
+	}
+}
@@ -3,6 +3,7 @@ package gosocketio
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 	"github.com/guhan121/golang-socketio/protocol"
@@ -14,40 +15,63 @@ var (
 )
 
 /**
-Send message packet to socket
+Encode message packet with its arguments for outgoing queue
 */
-func send(msg *protocol.Message, c *Channel, args interface{}) error {
+func encodePacket(msg *protocol.Message, args interface{}) (p *packet, err error) {
 	//preventing json/encoding "index out of range" panic
 	defer func() {
 		if r := recover(); r != nil {
 			log.Println("socket.io send panic: ", r)
+			err = fmt.Errorf("socket.io send panic: %v", r)
 		}
 	}()
 
 	if args != nil {
 		json, err := json.Marshal(&args)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		msg.Args = string(json)
 	}
 
 	command, err := protocol.Encode(msg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(c.out) == queueBufferSize {
-		return ErrorSocketOverflood
+	p = &packet{text: command}
+	if msg.Data != nil {
+		p.binary = [][]byte{append([]byte{4}, msg.Data...)}
 	}
-	err1 := c.conn.WriteMessage(command)
-	if err1 != nil {
-		return err1
+	return p, nil
+}
+
+/**
+Send message packet to socket
+*/
+func send(msg *protocol.Message, c *Channel, args interface{}) error {
+	msg.Namespace = c.namespace
+	p, err := encodePacket(msg, args)
+	if err != nil {
+		return err
 	}
-	if msg.Data != nil {
-		err1 = c.conn.WriteBytes(append([]byte{4}, msg.Data...))
+
+	return c.enqueue(p)
+}
+
+/**
+Send message packet to socket only if connection is writable right now,
+otherwise drop it
+*/
+func sendVolatile(msg *protocol.Message, c *Channel, args interface{}) error {
+	msg.Namespace = c.namespace
+	p, err := encodePacket(msg, args)
+	if err != nil {
+		return err
 	}
-	return err1
+
+	p.volatile = true
+	return c.enqueue(p)
 }
 
 /**
@@ -62,6 +86,20 @@ func (c *Channel) Emit(method string, args interface{}) error {
 	return send(msg, c, args)
 }
 
+/**
+Create packet based on given data and send it, unless outgoing queue is over
+high watermark, in which case packet is dropped
+Use it for data that is fine to lose, like cursor positions or telemetry
+*/
+func (c *Channel) EmitVolatile(method string, args interface{}) error {
+	msg := &protocol.Message{
+		Type:   protocol.MessageTypeEmit,
+		Method: method,
+	}
+
+	return sendVolatile(msg, c, args)
+}
+
 /**
 Create packet based on given data and send it
 */
@@ -85,27 +123,61 @@ func (c *Channel) EmitData(method string, data []byte, args interface{}) error {
 	return send(msg, c, new_args)
 }
 
+/**
+What happens to ack call when connection is lost before the response
+*/
+type AckOption int
+
+const (
+	/**
+	Fail ack call with ErrorAckDisconnected, default
+	*/
+	AckFailOnDisconnect AckOption = iota
+	/**
+	Send ack packet again after client reconnects, requires client
+	offline buffer, otherwise acts as AckFailOnDisconnect
+	*/
+	AckResendOnReconnect
+)
+
 /**
 Create ack packet based on given data and send it and receive response
+Timeout includes time spent in offline buffer and reconnection
 */
-func (c *Channel) Ack(method string, args interface{}, timeout time.Duration) (string, error) {
+func (c *Channel) Ack(method string, args interface{}, timeout time.Duration,
+	options ...AckOption) (string, error) {
+
 	msg := &protocol.Message{
-		Type:   protocol.MessageTypeAckRequest,
-		AckId:  c.ack.getNextId(),
-		Method: method,
+		Type:      protocol.MessageTypeAckRequest,
+		AckId:     c.ack.getNextId(),
+		Method:    method,
+		Namespace: c.namespace,
 	}
 
-	waiter := make(chan string)
+	p, err := encodePacket(msg, args)
+	if err != nil {
+		return "", err
+	}
+
+	resend := false
+	for _, option := range options {
+		resend = option == AckResendOnReconnect
+	}
+	waiter := newAckWaiter(p, resend)
 	c.ack.addWaiter(msg.AckId, waiter)
 
-	err := send(msg, c, args)
+	err = c.enqueue(p)
 	if err != nil {
 		c.ack.removeWaiter(msg.AckId)
+		return "", err
 	}
 
 	select {
-	case result := <-waiter:
+	case result := <-waiter.result:
+		c.ack.removeWaiter(msg.AckId)
 		return result, nil
+	case err := <-waiter.err:
+		return "", err
 	case <-time.After(timeout):
 		c.ack.removeWaiter(msg.AckId)
 		return "", ErrorSendTimeout
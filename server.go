@@ -1,14 +1,11 @@
 package gosocketio
 
 import (
-	"bytes"
-	"crypto/md5"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"math/rand"
 	"net/http"
+	"net/netip"
 	"sync"
 	"time"
 	"github.com/guhan121/golang-socketio/transport"
@@ -17,6 +14,9 @@ import (
 
 const (
 	HeaderForward = "X-Forwarded-For"
+
+	DefaultMaxPayload     = 1000000
+	DefaultMaxAttachments = 16
 )
 
 var (
@@ -39,6 +39,55 @@ type Server struct {
 	sidsLock sync.RWMutex
 
 	tr transport.Transport
+
+	//outgoing queue settings of every channel, set before serving
+	QueueConfig
+	overflooded overfloodRegistry
+
+	//allowed origins and CORS settings, nil allows any origin, set before serving
+	Origins *OriginPolicy
+	//handshake authorization, nil accepts every request, set before serving
+	Authorize AuthorizeFunc
+	//namespace connect authorization with auth payload of client, nil
+	//connects default namespace at once, set before serving
+	AuthorizeConnect ConnectAuthorizeFunc
+	//validation of fresh auth payload sent with OnTokenRefresh, nil uses
+	//AuthorizeConnect, set before serving
+	Reauthorize ConnectAuthorizeFunc
+	//disconnect channel when its identity expires, see Expiring, set before
+	//serving
+	DisconnectOnExpiry bool
+	//ask client for fresh auth payload with OnTokenRequest this long before
+	//its identity expires, right away if less is left, zero does not ask
+	//Channels not refreshed in time are disconnected as with
+	//DisconnectOnExpiry, set before serving
+	RefreshBefore time.Duration
+
+	//networks of proxies trusted to set ProxyHeader, set before serving
+	TrustedProxies []netip.Prefix
+	//header trusted proxies set, HeaderForward or HeaderForwarded, the other
+	//one is ignored, empty means HeaderForward, set before serving
+	ProxyHeader string
+	//allowed and denied networks of clients, nil allows every client, lists
+	//may be replaced at runtime
+	IpFilter *IpFilter
+
+	//generator of session ids, nil uses GenerateSid, set before serving
+	GenerateSid SidGenerator
+
+	//connection limits and load shedding, nil means no limits, set before
+	//serving
+	Limits  *ConnectionLimits
+	limiter connLimiter
+
+	//rate limits of incoming events of every channel, nil means no limits,
+	//set before serving
+	EventLimits *EventLimits
+
+	//max size of incoming message and max binary attachments of incoming
+	//packet, zero means no limit, set before serving
+	MaxPayload     int64
+	MaxAttachments int
 }
 
 /**
@@ -46,18 +95,48 @@ Close current channel
  */
 func (c *Channel) Close() {
 	if c.server != nil {
-		closeChannel(c, &c.server.methods)
+		c.server.transition(c, StateClosing, StateOpen)
+		closeChannel(c, &c.server.methods, ReasonServerNamespaceDisconnect)
 	}
 }
 
 /**
-Get ip of socket client
+Disconnect client from the namespace with DISCONNECT packet, client gets it
+as "io server disconnect" and does not reconnect
+closeTransport sends engine.io close packet after it. Server has one namespace
+per connection, so connection is closed in both cases, once packets are
+written or ping timeout expires. Returns right away, channel is closing then
+and packets are written in background, so it may be called from handlers
+Reason, ReasonServerNamespaceDisconnect if empty, is reported by
+DisconnectReason to disconnection handlers
 */
-func (c *Channel) Ip() string {
-	forward := c.RequestHeader().Get(HeaderForward)
-	if forward != "" {
-		return forward
+func (c *Channel) Disconnect(reason string, closeTransport bool) {
+	if c.server == nil {
+		return
+	}
+	if reason == "" {
+		reason = ReasonServerNamespaceDisconnect
 	}
+
+	c.closing(reason)
+	c.server.transition(c, StateClosing, StateOpen)
+	go func() {
+		if conn, _ := c.current(); conn != nil {
+			_, timeout := conn.PingParams()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			c.flushClose(ctx, closeTransport, c.namespace)
+			cancel()
+		}
+		closeChannel(c, &c.server.methods, reason)
+	}()
+}
+
+/**
+Get ip of socket client, forwarded headers are used only when they come from
+trusted proxy of server, see Server.TrustedProxies
+Zero address is returned if it is unknown
+*/
+func (c *Channel) Ip() netip.Addr {
 	return c.ip
 }
 
@@ -219,6 +298,33 @@ func (s *Server) BroadcastTo(room, method string, args interface{}) {
 	}
 }
 
+func (c *Channel) BroadcastVolatileTo(room, method string, args interface{}) {
+	if c.server == nil {
+		return
+	}
+	c.server.BroadcastVolatileTo(room, method, args)
+}
+
+/**
+Broadcast volatile message to all room channels, channels which are not
+writable right now drop it
+*/
+func (s *Server) BroadcastVolatileTo(room, method string, args interface{}) {
+	s.channelsLock.RLock()
+	defer s.channelsLock.RUnlock()
+
+	roomChannels, ok := s.channels[room]
+	if !ok {
+		return
+	}
+
+	for cn := range roomChannels {
+		if cn.IsAlive() {
+			go cn.EmitVolatile(method, args)
+		}
+	}
+}
+
 /**
 Broadcast to all clients
 */
@@ -234,16 +340,18 @@ func (s *Server) BroadcastToAll(method string, args interface{}) {
 }
 
 /**
-Generate new id for socket.io connection
+Broadcast volatile message to all clients, channels which are not writable
+right now drop it
 */
-func generateNewId(custom string) string {
-	hash := fmt.Sprintf("%s %s %n %n", custom, time.Now(), rand.Uint32(), rand.Uint32())
-	buf := bytes.NewBuffer(nil)
-	sum := md5.Sum([]byte(hash))
-	encoder := base64.NewEncoder(base64.URLEncoding, buf)
-	encoder.Write(sum[:])
-	encoder.Close()
-	return buf.String()[:20]
+func (s *Server) BroadcastVolatileToAll(method string, args interface{}) {
+	s.sidsLock.RLock()
+	defer s.sidsLock.RUnlock()
+
+	for _, cn := range s.sids {
+		if cn.IsAlive() {
+			go cn.EmitVolatile(method, args)
+		}
+	}
 }
 
 /**
@@ -282,31 +390,38 @@ func onDisconnectCleanup(c *Channel) {
 	defer c.server.sidsLock.Unlock()
 
 	delete(c.server.sids, c.Id())
+	if c.release != nil {
+		c.release()
+	}
+
+	c.aliveLock.Lock()
+	c.stopExpiry()
+	c.aliveLock.Unlock()
 }
 
+/**
+Send engine.io open packet followed by connect packet of default namespace
+*/
 func (s *Server) SendOpenSequence(c *Channel) {
+	s.sendOpen(c)
+	c.enqueueText(protocol.MustEncode(&protocol.Message{Type: protocol.MessageTypeEmpty}))
+}
+
+/**
+Send engine.io open packet with header of channel
+*/
+func (s *Server) sendOpen(c *Channel) {
 	jsonHdr, err := json.Marshal(&c.Header)
 	if err != nil {
 		panic(err)
 	}
 
-	mustEncode := protocol.MustEncode(
+	c.enqueueText(protocol.MustEncode(
 		&protocol.Message{
 			Type: protocol.MessageTypeOpen,
 			Args: string(jsonHdr),
 		},
-	)
-
-	rs := []byte(mustEncode)
-	for _, r := range rs {
-		c.out <- r
-	}
-	encode := protocol.MustEncode(&protocol.Message{Type: protocol.MessageTypeEmpty})
-
-	rs1 := []byte(encode)
-	for _, r := range rs1 {
-		c.out <- r
-	}
+	))
 }
 
 /**
@@ -315,41 +430,122 @@ Setup event loop for given connection
 func (s *Server) SetupEventLoop(conn transport.Connection, remoteAddr string,
 	requestHeader http.Header) {
 
+	ip := resolveClientIp(remoteAddr, requestHeader, s.ProxyHeader, s.TrustedProxies)
+	s.setupEventLoop(conn, requestHeader, admission{ip: ip})
+}
+
+/**
+What server learned about request before the upgrade
+*/
+type admission struct {
+	ip       netip.Addr
+	identity interface{}
+	//error of authorization, namespace is refused with CONNECT_ERROR
+	refusal error
+	//called when connection admitted by limits is closed, nil if none
+	release func()
+}
+
+/**
+Setup event loop for given connection admitted by checks of request
+*/
+func (s *Server) setupEventLoop(conn transport.Connection, requestHeader http.Header,
+	a admission) {
+
 	interval, timeout := conn.PingParams()
 	hdr := Header{
-		Sid:          generateNewId(remoteAddr),
 		Upgrades:     []string{},
 		PingInterval: int(interval / time.Millisecond),
 		PingTimeout:  int(timeout / time.Millisecond),
 	}
 
+	if limited, ok := conn.(transport.LimitedConnection); ok && s.MaxPayload > 0 {
+		limited.SetReadLimit(s.MaxPayload)
+		hdr.MaxPayload = s.MaxPayload
+	}
+
 	c := &Channel{}
 	c.conn = conn
-	c.ip = remoteAddr
+	c.ip = a.ip
 	c.requestHeader = requestHeader
-	c.initChannel()
+	c.identity = a.identity
+	c.release = a.release
+	c.maxAttachments = s.MaxAttachments
+	c.initChannel(&s.methods, s.QueueConfig, &s.overflooded)
 
 	c.server = s
 	c.Header = hdr
+	c.state = StateConnecting
+	if err := s.allocateSid(c); err != nil {
+		conn.Close()
+		if a.release != nil {
+			a.release()
+		}
+		return
+	}
 
-	s.SendOpenSequence(c)
-
-	go inLoop(c, &s.methods)
-	go outLoop(c, &s.methods)
-
-	s.callLoopEvent(c, OnConnection)
+	s.sendOpen(c)
+
+	ls := c.loops()
+	go procLoop(c, &s.methods, ls)
+	go inLoop(c, &s.methods, ls)
+	go outLoop(c, &s.methods, ls)
+
+	switch {
+	case a.refusal != nil:
+		s.refuseConnect(c, a.refusal)
+	case s.AuthorizeConnect != nil:
+		//wait for connect packet of client
+		time.AfterFunc(timeout, func() {
+			if c.State() == StateConnecting {
+				closeChannel(c, &s.methods, ReasonTransportClose)
+			}
+		})
+	default:
+		c.enqueueText(protocol.MustEncode(&protocol.Message{Type: protocol.MessageTypeEmpty}))
+		s.open(c)
+	}
 }
 
 /**
 implements ServeHTTP function from http.Handler
 */
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Origins != nil && !s.Origins.handle(w, r) {
+		return
+	}
+
+	a := admission{ip: s.clientIp(r)}
+	if s.IpFilter != nil && !s.IpFilter.Allowed(a.ip) {
+		http.Error(w, ErrorIpNotAllowed.Error(), http.StatusForbidden)
+		return
+	}
+
+	release := s.admit(w, r, a.ip)
+	if release == nil {
+		return
+	}
+	a.release = release
+
+	if s.Authorize != nil {
+		var err error
+		if a.identity, err = s.Authorize(r); err != nil {
+			if _, refused := err.(*ConnectError); !refused {
+				release()
+				writeHandshakeError(w, err)
+				return
+			}
+			a.refusal = err
+		}
+	}
+
 	conn, err := s.tr.HandleConnection(w, r)
 	if err != nil {
+		release()
 		return
 	}
 
-	s.SetupEventLoop(conn, r.RemoteAddr, r.Header)
+	s.setupEventLoop(conn, r.Header, a)
 	s.tr.Serve(w, r)
 }
 
@@ -363,6 +559,21 @@ func (s *Server) AmountOfSids() int64 {
 	return int64(len(s.sids))
 }
 
+/**
+Get amount of channels with outgoing queue over high watermark
+*/
+func (s *Server) AmountOfOverflooded() int64 {
+	return s.overflooded.amount()
+}
+
+/**
+Get channels with outgoing queue over high watermark, with queue depth and
+time spent over the watermark
+*/
+func (s *Server) ListOverflooded() []OverfloodedChannel {
+	return s.overflooded.list()
+}
+
 /**
 Get amount of rooms with at least one channel(or sid) joined
 */
@@ -380,11 +591,16 @@ func NewServer(tr transport.Transport) *Server {
 	s := Server{}
 	s.initMethods()
 	s.tr = tr
+	s.QueueBufferSize = queueBufferSize
+	s.MaxPayload = DefaultMaxPayload
+	s.MaxAttachments = DefaultMaxAttachments
 	s.channels = make(map[string]map[*Channel]struct{})
 	s.rooms = make(map[*Channel]map[string]struct{})
 	s.sids = make(map[string]*Channel)
 	s.onConnection = onConnectStore
 	s.onDisconnection = onDisconnectCleanup
+	s.onAbort = onDisconnectCleanup
+	s.allowEvent = s.filterEvent
 
 	return &s
 }
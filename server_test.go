@@ -0,0 +1,163 @@
+package gosocketio
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerDisconnect(t *testing.T) {
+	serverReason := make(chan string, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.On("kick", func(h *Channel) {
+		h.Disconnect("kicked", false)
+	})
+	server.On(OnDisconnection, func(h *Channel) {
+		serverReason <- h.DisconnectReason()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	clientReason := make(chan string, 1)
+	reconnects := int32(0)
+	c, err := Dial(context.Background(), ts.URL,
+		WithReconnect(&ReconnectConfig{InitialDelay: time.Millisecond}),
+		WithHandler(OnDisconnection, func(h *Channel) {
+			clientReason <- h.DisconnectReason()
+		}),
+		WithHandler(OnReconnectAttempt, func(h *Channel) {
+			atomic.AddInt32(&reconnects, 1)
+		}),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Emit("kick", nil))
+	select {
+	case reason := <-clientReason:
+		assert.Equal(t, ReasonServerDisconnect, reason)
+	case <-time.After(5 * time.Second):
+		t.Fatal("client was not disconnected")
+	}
+	assert.Equal(t, "kicked", <-serverReason)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&reconnects))
+	assert.False(t, c.IsAlive())
+}
+
+/**
+Connection which never writes, so flush waits until ping timeout
+*/
+type stalledConnection struct {
+	nopConnection
+}
+
+func (c *stalledConnection) PingParams() (time.Duration, time.Duration) {
+	return time.Second, 500 * time.Millisecond
+}
+
+func TestServerDisconnectAsync(t *testing.T) {
+	reasons := make(chan string, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.On(OnDisconnection, func(h *Channel) {
+		reasons <- h.DisconnectReason()
+	})
+
+	conn := &stalledConnection{}
+	c := &Channel{}
+	c.conn = conn
+	c.server = server
+	c.initChannel(&server.methods, QueueConfig{}, &server.overflooded)
+	c.state = StateOpen
+
+	start := time.Now()
+	c.Disconnect("kicked", true)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, StateClosing, c.State())
+
+	select {
+	case reason := <-reasons:
+		assert.Equal(t, "kicked", reason)
+	case <-time.After(10 * time.Second):
+		t.Fatal("channel was not closed after flush timeout")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conn.closed))
+}
+
+func TestServerAuthorize(t *testing.T) {
+	identities := make(chan interface{}, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.Authorize = func(r *http.Request) (interface{}, error) {
+		if r.URL.Query().Get("token") != "secret" {
+			return nil, &HandshakeError{Status: http.StatusForbidden, Body: "bad token"}
+		}
+		return "alice", nil
+	}
+	server.On(OnConnection, func(h *Channel) {
+		identities <- h.Identity()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/socket.io/?EIO=3&transport=websocket")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "bad token\n", string(body))
+
+	c, err := Dial(context.Background(), ts.URL, WithQuery(url.Values{"token": {"secret"}}))
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, "alice", <-identities)
+}
+
+func TestServerMaxPayload(t *testing.T) {
+	reasons := make(chan string, 1)
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.MaxPayload = 64
+	server.MaxAttachments = 1
+	server.On(OnDisconnection, func(h *Channel) {
+		reasons <- h.DisconnectReason()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	wsUrl := "ws" + strings.TrimPrefix(ts.URL, "http") + socketioUrl
+	for _, tc := range []struct {
+		frames []string
+		reason string
+	}{
+		{[]string{`42["chat","` + strings.Repeat("x", 64) + `"]`}, ReasonPayloadTooLarge},
+		{[]string{`452-["chat",{"_placeholder":true,"num":0}]`}, ReasonTooManyAttachments},
+	} {
+		socket, _, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+		require.NoError(t, err)
+		_, open, err := socket.ReadMessage()
+		require.NoError(t, err)
+		assert.Contains(t, string(open), `"maxPayload":64`)
+
+		for _, frame := range tc.frames {
+			require.NoError(t, socket.WriteMessage(websocket.TextMessage, []byte(frame)))
+		}
+		select {
+		case reason := <-reasons:
+			assert.Equal(t, tc.reason, reason)
+		case <-time.After(5 * time.Second):
+			t.Fatal("client was not disconnected")
+		}
+		socket.Close()
+	}
+}
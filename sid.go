@@ -0,0 +1,63 @@
+package gosocketio
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+const (
+	//random bytes of default sid, 20 characters when encoded
+	sidSize = 15
+	//attempts to get sid which is not in use before connection is refused
+	sidAttempts = 8
+)
+
+var (
+	ErrorSidNotUnique = errors.New("Can not generate unique sid")
+)
+
+/**
+Generator of session ids, generated ids must be unpredictable and url safe
+*/
+type SidGenerator func() (string, error)
+
+/**
+Default sid generator, random bytes of crypto/rand in url safe base64
+*/
+func GenerateSid() (string, error) {
+	buf := make([]byte, sidSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+/**
+Generate sid which is not in use, set it to header of channel and register
+the channel with it, so sid is reserved before the channel gets open
+*/
+func (s *Server) allocateSid(c *Channel) error {
+	generate := s.GenerateSid
+	if generate == nil {
+		generate = GenerateSid
+	}
+
+	s.sidsLock.Lock()
+	defer s.sidsLock.Unlock()
+
+	for i := 0; i < sidAttempts; i++ {
+		sid, err := generate()
+		if err != nil {
+			return err
+		}
+		if _, ok := s.sids[sid]; sid == "" || ok {
+			continue
+		}
+
+		c.Header.Sid = sid
+		s.sids[sid] = c
+		return nil
+	}
+	return ErrorSidNotUnique
+}
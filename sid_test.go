@@ -0,0 +1,43 @@
+package gosocketio
+
+import (
+	"testing"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSid(t *testing.T) {
+	seen := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		sid, err := GenerateSid()
+		require.NoError(t, err)
+		assert.Len(t, sid, 20)
+		assert.NotContains(t, seen, sid)
+		seen[sid] = struct{}{}
+	}
+}
+
+func TestAllocateSidUnique(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	sids := []string{"a", "a", "", "b", "a", "b"}
+	server.GenerateSid = func() (string, error) {
+		sid := sids[0]
+		sids = sids[1:]
+		return sid, nil
+	}
+
+	first, second := &Channel{}, &Channel{}
+	require.NoError(t, server.allocateSid(first))
+	require.NoError(t, server.allocateSid(second))
+	assert.Equal(t, "a", first.Id())
+	assert.Equal(t, "b", second.Id())
+
+	c, err := server.GetChannel("b")
+	require.NoError(t, err)
+	assert.Equal(t, second, c)
+
+	server.GenerateSid = func() (string, error) { return "a", nil }
+	assert.Equal(t, ErrorSidNotUnique, server.allocateSid(&Channel{}))
+}
@@ -0,0 +1,92 @@
+package gosocketio
+
+/**
+Lifecycle state of channel
+*/
+type State int
+
+const (
+	StateConnecting State = iota
+	StateOpen
+	StateReconnecting
+	StateClosing
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateOpen:
+		return "open"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosing:
+		return "closing"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+/**
+Argument of state change event
+*/
+type StateChange struct {
+	From State
+	To   State
+}
+
+/**
+Get current lifecycle state of channel
+*/
+func (c *Channel) State() State {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+
+	return c.state
+}
+
+/**
+Move channel to given state if it is in one of from states, any state if
+none given, returns false if state was not changed
+*/
+func (m *methods) transition(c *Channel, to State, from ...State) bool {
+	c.aliveLock.Lock()
+	prev := c.state
+	ok := prev != to && len(from) == 0
+	for _, state := range from {
+		if prev == state && prev != to {
+			ok = true
+		}
+	}
+	if ok {
+		c.state = to
+	}
+	c.aliveLock.Unlock()
+
+	if ok {
+		m.stateChanged(c, prev, to)
+	}
+	return ok
+}
+
+/**
+Fire state change event, connection event when channel gets open and
+disconnection event when open channel is lost, so each fires once per
+transition
+*/
+func (m *methods) stateChanged(c *Channel, from, to State) {
+	m.callEvent(c, OnStateChange, StateChange{from, to})
+
+	if to == StateOpen {
+		m.callLoopEvent(c, OnConnection)
+	}
+	if (from == StateOpen || from == StateClosing) &&
+		(to == StateClosed || to == StateReconnecting) {
+		m.callLoopEvent(c, OnDisconnection)
+	}
+	if from == StateConnecting && to == StateClosed && m.onAbort != nil {
+		m.onAbort(c)
+	}
+}
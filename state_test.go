@@ -0,0 +1,74 @@
+package gosocketio
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientStateTransitions(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	server.On("drop", func(h *Channel) {
+		h.Close()
+	})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	var lock sync.Mutex
+	var changes []string
+	connections, disconnections := 0, 0
+	reconnected := make(chan struct{}, 1)
+	c, err := Dial(context.Background(), ts.URL,
+		WithReconnect(&ReconnectConfig{InitialDelay: time.Millisecond}),
+		WithHandler(OnStateChange, func(h *Channel, change StateChange) {
+			lock.Lock()
+			defer lock.Unlock()
+			changes = append(changes, change.From.String()+">"+change.To.String())
+		}),
+		WithHandler(OnConnection, func(h *Channel) {
+			lock.Lock()
+			defer lock.Unlock()
+			connections++
+		}),
+		WithHandler(OnDisconnection, func(h *Channel) {
+			lock.Lock()
+			defer lock.Unlock()
+			disconnections++
+		}),
+		WithHandler(OnReconnect, func(h *Channel) {
+			reconnected <- struct{}{}
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, StateOpen, c.State())
+
+	require.NoError(t, c.Emit("drop", nil))
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not reconnect")
+	}
+	assert.Equal(t, StateOpen, c.State())
+
+	c.Close()
+	assert.Equal(t, StateClosed, c.State())
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{
+		"closed>connecting",
+		"connecting>open",
+		"open>reconnecting",
+		"reconnecting>open",
+		"open>closing",
+		"closing>closed",
+	}, changes)
+	assert.Equal(t, 2, connections)
+	assert.Equal(t, 2, disconnections)
+}
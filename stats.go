@@ -0,0 +1,246 @@
+package gosocketio
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/guhan121/golang-socketio/protocol"
+)
+
+/**
+Snapshot of channel counters
+*/
+type Stats struct {
+	PacketsIn       uint64
+	PacketsOut      uint64
+	BytesIn         uint64
+	BytesOut        uint64
+	BinaryFramesIn  uint64
+	BinaryFramesOut uint64
+
+	//packets dropped as volatile or by overflow policy of queue or offline
+	//buffer, over all connections of channel like the counters above
+	PacketsDropped uint64
+
+	AcksPending int
+	//packets queued by current connection
+	QueueDepth int
+	//biggest amount of packets queued at once, over all connections
+	QueueHighWater int
+
+	//round trip time measured by the last ping/pong exchange, zero if none yet
+	RTT time.Duration
+}
+
+/**
+Traffic counters of one channel, updated by inLoop and outLoop
+*/
+type trafficStats struct {
+	packetsIn       atomic.Uint64
+	packetsOut      atomic.Uint64
+	bytesIn         atomic.Uint64
+	bytesOut        atomic.Uint64
+	binaryFramesIn  atomic.Uint64
+	binaryFramesOut atomic.Uint64
+	rtt             atomic.Int64
+
+	//dropped packets and high watermark of queues of previous connections
+	queueDropped   atomic.Uint64
+	queueHighWater atomic.Int64
+}
+
+/**
+Keep counters of queue of closed connection, so they last across reconnects
+*/
+func (s *trafficStats) retireQueue(q *outQueue) {
+	s.queueDropped.Add(q.droppedCount())
+	if high := int64(q.highWaterMark()); high > s.queueHighWater.Load() {
+		s.queueHighWater.Store(high)
+	}
+}
+
+/**
+Count received frame
+*/
+func (s *trafficStats) received(size int, binary bool) {
+	if binary {
+		s.binaryFramesIn.Add(1)
+	} else {
+		s.packetsIn.Add(1)
+	}
+	s.bytesIn.Add(uint64(size))
+}
+
+/**
+Count packet written with all its binary frames
+*/
+func (s *trafficStats) sent(p *packet) {
+	size := len(p.text)
+	for _, b := range p.binary {
+		size += len(b)
+	}
+	s.packetsOut.Add(1)
+	s.binaryFramesOut.Add(uint64(len(p.binary)))
+	s.bytesOut.Add(uint64(size))
+}
+
+/**
+Matches written pings with received pongs to measure round trip time
+*/
+type pingTracker struct {
+	sent    []time.Time
+	waiters []chan time.Duration
+	lock    sync.Mutex
+}
+
+/**
+Ping is about to be written to the socket, recorded before the write so pong
+coming right after it finds its ping. Returns send time for pingFailed
+*/
+func (pt *pingTracker) pingSent() time.Time {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	now := time.Now()
+	pt.sent = append(pt.sent, now)
+	return now
+}
+
+/**
+Writing of ping recorded by pingSent failed, forget it
+*/
+func (pt *pingTracker) pingFailed(at time.Time) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	for i, sent := range pt.sent {
+		if sent == at {
+			pt.sent = append(pt.sent[:i], pt.sent[i+1:]...)
+			return
+		}
+	}
+}
+
+/**
+Pong was received, returns round trip time of the oldest unanswered ping
+*/
+func (pt *pingTracker) pongReceived() (time.Duration, bool) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	if len(pt.sent) == 0 {
+		return 0, false
+	}
+	rtt := time.Since(pt.sent[0])
+	pt.sent = pt.sent[1:]
+
+	for _, w := range pt.waiters {
+		w <- rtt
+	}
+	pt.waiters = nil
+
+	return rtt, true
+}
+
+/**
+Forget pings of previous connection
+*/
+func (pt *pingTracker) reset() {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	pt.sent = nil
+}
+
+func (pt *pingTracker) addWaiter(w chan time.Duration) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	pt.waiters = append(pt.waiters, w)
+}
+
+func (pt *pingTracker) removeWaiter(w chan time.Duration) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+
+	for i, cur := range pt.waiters {
+		if cur == w {
+			pt.waiters = append(pt.waiters[:i], pt.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+/**
+Pong received by inLoop, update round trip time
+*/
+func (c *Channel) onPong() {
+	if rtt, ok := c.pings.pongReceived(); ok {
+		c.stats.rtt.Store(int64(rtt))
+	}
+}
+
+/**
+Send ping and wait for pong, returns measured round trip time
+*/
+func (c *Channel) Ping(ctx context.Context) (time.Duration, error) {
+	waiter := make(chan time.Duration, 1)
+	c.pings.addWaiter(waiter)
+
+	if err := c.enqueueText(protocol.PingMessage); err != nil {
+		c.pings.removeWaiter(waiter)
+		return 0, err
+	}
+
+	select {
+	case rtt := <-waiter:
+		return rtt, nil
+	case <-ctx.Done():
+		c.pings.removeWaiter(waiter)
+		return 0, ctx.Err()
+	}
+}
+
+/**
+Get snapshot of channel counters
+*/
+func (c *Channel) Stats() Stats {
+	if c.engine != nil {
+		//manager socket, traffic is counted by shared connection
+		stats := c.engine.Stats()
+		stats.AcksPending = c.ack.amount()
+		if c.offline != nil {
+			stats.PacketsDropped += c.offline.droppedCount()
+		}
+		return stats
+	}
+
+	stats := Stats{
+		PacketsIn:       c.stats.packetsIn.Load(),
+		PacketsOut:      c.stats.packetsOut.Load(),
+		BytesIn:         c.stats.bytesIn.Load(),
+		BytesOut:        c.stats.bytesOut.Load(),
+		BinaryFramesIn:  c.stats.binaryFramesIn.Load(),
+		BinaryFramesOut: c.stats.binaryFramesOut.Load(),
+		AcksPending:     c.ack.amount(),
+		RTT:             time.Duration(c.stats.rtt.Load()),
+	}
+	c.aliveLock.Lock()
+	out := c.out
+	stats.PacketsDropped = c.stats.queueDropped.Load()
+	stats.QueueHighWater = int(c.stats.queueHighWater.Load())
+	c.aliveLock.Unlock()
+	if out != nil {
+		stats.PacketsDropped += out.droppedCount()
+		stats.QueueDepth = out.len()
+		if high := out.highWaterMark(); high > stats.QueueHighWater {
+			stats.QueueHighWater = high
+		}
+	}
+	if c.offline != nil {
+		stats.PacketsDropped += c.offline.droppedCount()
+	}
+	return stats
+}
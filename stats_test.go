@@ -0,0 +1,99 @@
+package gosocketio
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guhan121/golang-socketio/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrafficStats(t *testing.T) {
+	var s trafficStats
+	s.received(10, false)
+	s.received(5, true)
+	s.sent(&packet{text: "42[]", binary: [][]byte{{4, 1, 2}}})
+
+	assert.Equal(t, uint64(1), s.packetsIn.Load())
+	assert.Equal(t, uint64(1), s.binaryFramesIn.Load())
+	assert.Equal(t, uint64(15), s.bytesIn.Load())
+	assert.Equal(t, uint64(1), s.packetsOut.Load())
+	assert.Equal(t, uint64(1), s.binaryFramesOut.Load())
+	assert.Equal(t, uint64(7), s.bytesOut.Load())
+}
+
+func TestStatsAcrossConnections(t *testing.T) {
+	c := &Channel{}
+	config := QueueConfig{QueueBufferSize: 2, OverflowPolicy: OverflowDropNewest}
+	c.initChannel(&methods{}, config, &overfloodRegistry{})
+	c.out.push(&packet{text: "a"})
+	c.out.push(&packet{text: "b"})
+	assert.Equal(t, ErrorPacketDropped, c.out.push(&packet{text: "c"}))
+	c.out.close()
+
+	c.initChannel(&methods{}, config, &overfloodRegistry{})
+	c.out.push(&packet{text: "d"})
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.PacketsDropped)
+	assert.Equal(t, 2, stats.QueueHighWater)
+	assert.Equal(t, 1, stats.QueueDepth)
+}
+
+func TestPingTracker(t *testing.T) {
+	var pt pingTracker
+
+	_, ok := pt.pongReceived()
+	assert.False(t, ok)
+
+	waiter := make(chan time.Duration, 1)
+	pt.addWaiter(waiter)
+	pt.pingSent()
+
+	rtt, ok := pt.pongReceived()
+	assert.True(t, ok)
+	assert.Equal(t, rtt, <-waiter)
+
+	_, ok = pt.pongReceived()
+	assert.False(t, ok)
+}
+
+func TestPingTrackerFailed(t *testing.T) {
+	var pt pingTracker
+
+	first := pt.pingSent()
+	pt.pingSent()
+	pt.pingFailed(first)
+	assert.Len(t, pt.sent, 1)
+
+	_, ok := pt.pongReceived()
+	assert.True(t, ok)
+	_, ok = pt.pongReceived()
+	assert.False(t, ok)
+}
+
+func TestPingLoop(t *testing.T) {
+	server := NewServer(transport.GetDefaultWebsocketTransport())
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	c, err := Dial(context.Background(), ts.URL)
+	require.NoError(t, err)
+	defer c.Close()
+
+	for i := 0; i < 500; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		rtt, err := c.Ping(ctx)
+		cancel()
+		require.NoError(t, err, "ping %d", i)
+		assert.True(t, rtt > 0 && rtt < time.Second, rtt)
+	}
+
+	stats := c.Stats()
+	assert.True(t, stats.RTT > 0 && stats.RTT < time.Second, stats.RTT)
+	c.pings.lock.Lock()
+	assert.Empty(t, c.pings.sent)
+	c.pings.lock.Unlock()
+}
@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"context"
 	"net/http"
 	"time"
 	"github.com/gorilla/websocket"
@@ -52,3 +53,40 @@ type Transport interface {
 	*/
 	Serve(w http.ResponseWriter, r *http.Request)
 }
+
+/**
+Transport which can abort connecting when context is done
+*/
+type ContextTransport interface {
+	Transport
+
+	/**
+	Get client connection, abort when ctx is done
+	*/
+	ConnectContext(ctx context.Context, url string) (conn Connection, err error)
+}
+
+/**
+Transport which tells its engine.io name, used as transport param of url
+*/
+type NamedTransport interface {
+	Transport
+
+	/**
+	Get engine.io transport name, for example "websocket" or "polling"
+	*/
+	Name() string
+}
+
+/**
+Connection which limits size of incoming messages
+*/
+type LimitedConnection interface {
+	Connection
+
+	/**
+	Set max size of incoming message, bigger one fails GetMessage with
+	ErrorMessageTooBig and closes connection
+	*/
+	SetReadLimit(limit int64)
+}
@@ -1,10 +1,14 @@
 package transport
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"github.com/gorilla/websocket"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
 	"time"
 	"sync"
 )
@@ -25,6 +29,8 @@ var (
 	ErrorPacketWrong       = errors.New("Wrong packet type error")
 	ErrorMethodNotAllowed  = errors.New("Method not allowed")
 	ErrorHttpUpgradeFailed = errors.New("Http upgrade failed")
+	ErrorOriginNotAllowed  = errors.New("Origin not allowed")
+	ErrorMessageTooBig     = errors.New("Message too big")
 )
 
 type WebsocketConnection struct {
@@ -36,6 +42,9 @@ type WebsocketConnection struct {
 func (wsc *WebsocketConnection) GetMessage() (message []byte, messageType int, err error) {
 	wsc.socket.SetReadDeadline(time.Now().Add(wsc.transport.ReceiveTimeout))
 	msgType, reader, err := wsc.socket.NextReader()
+	if err == websocket.ErrReadLimit {
+		return nil, msgType, ErrorMessageTooBig
+	}
 	if err != nil {
 		//fmt.Println("wsc.socket.NextReader")
 		return nil, msgType, err
@@ -51,6 +60,9 @@ func (wsc *WebsocketConnection) GetMessage() (message []byte, messageType int, e
 
 	data, err := ioutil.ReadAll(reader)
 	//fmt.Println(data)
+	if err == websocket.ErrReadLimit {
+		return nil, msgType, ErrorMessageTooBig
+	}
 	if err != nil {
 		//fmt.Println("ErrorBadBuffer")
 		return nil, msgType, ErrorBadBuffer
@@ -93,6 +105,14 @@ func (wsc *WebsocketConnection) WriteBytes(bytes []byte) error {
 	}
 	return nil
 }
+/**
+Set max size of incoming message, peer gets close frame 1009 when it is
+exceeded
+*/
+func (wsc *WebsocketConnection) SetReadLimit(limit int64) {
+	wsc.socket.SetReadLimit(limit)
+}
+
 func (wsc *WebsocketConnection) Close() {
 	wsc.socket.Close()
 }
@@ -110,11 +130,43 @@ type WebsocketTransport struct {
 	BufferSize int
 
 	RequestHeader http.Header
+
+	//client side only, nil config and zero timeout mean defaults
+	TLSClientConfig  *tls.Config
+	HandshakeTimeout time.Duration
+
+	//client side only, proxy for the request, nil means no proxy
+	//use http.ProxyURL for fixed http CONNECT proxy or
+	//http.ProxyFromEnvironment for HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Proxy func(*http.Request) (*url.URL, error)
+	//client side only, custom dial of tcp connection, nil means net.Dialer
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	//server side only, checks Origin header of upgrade request, nil allows any
+	CheckOrigin func(r *http.Request) bool
+}
+
+func (wst *WebsocketTransport) Name() string {
+	return "websocket"
 }
 
 func (wst *WebsocketTransport) Connect(url string) (conn Connection, err error) {
-	dialer := websocket.Dialer{}
-	socket, _, err := dialer.Dial(url, wst.RequestHeader)
+	return wst.ConnectContext(context.Background(), url)
+}
+
+/**
+Get client connection, connecting is aborted when ctx is done
+*/
+func (wst *WebsocketTransport) ConnectContext(ctx context.Context, url string) (conn Connection, err error) {
+	dialer := websocket.Dialer{
+		TLSClientConfig:  wst.TLSClientConfig,
+		HandshakeTimeout: wst.HandshakeTimeout,
+		Proxy:            wst.Proxy,
+		NetDialContext:   wst.NetDialContext,
+		ReadBufferSize:   wst.BufferSize,
+		WriteBufferSize:  wst.BufferSize,
+	}
+	socket, _, err := dialer.DialContext(ctx, url, wst.RequestHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +182,19 @@ func (wst *WebsocketTransport) HandleConnection(
 		return nil, ErrorMethodNotAllowed
 	}
 
-	socket, err := websocket.Upgrade(w, r, nil, wst.BufferSize, wst.BufferSize)
+	if wst.CheckOrigin != nil && !wst.CheckOrigin(r) {
+		http.Error(w, upgradeFailed+ErrorOriginNotAllowed.Error(), http.StatusForbidden)
+		return nil, ErrorOriginNotAllowed
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  wst.BufferSize,
+		WriteBufferSize: wst.BufferSize,
+		CheckOrigin:     func(r *http.Request) bool { return true },
+		//error response is written below
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {},
+	}
+	socket, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, upgradeFailed+err.Error(), 503)
 		return nil, ErrorHttpUpgradeFailed
@@ -146,6 +210,7 @@ func (wst *WebsocketTransport) Serve(w http.ResponseWriter, r *http.Request) {}
 
 /**
 Returns websocket connection with default params
+Client connections use no proxy, set Proxy to use one
 */
 func GetDefaultWebsocketTransport() *WebsocketTransport {
 	return &WebsocketTransport{